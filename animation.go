@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+// The limits of an animation, and how long each frame and the last one are
+// shown, in hundredths of a second
+const maxAnimationFrames = 1000
+const minAnimationSide = 100
+const maxAnimationSide = 4000
+const animationFrameDelay = 10
+const animationLastFrameDelay = 200
+
+var animationAxes = []string{"Linear", "Log"}
+
+// animationOptions are how a trajectory is animated
+type animationOptions struct {
+	frames int
+	width  int
+	height int
+	log    bool
+}
+
+// animationFrames runs report's value again, sending a report every so many
+// steps, and charts the trajectory so far in each report. The axes are those
+// of the whole trajectory, so the chart fills in from left to right.
+func animationFrames(report *sequenceProgress, o animationOptions) ([]*image.Paletted, error) {
+	every := max(1, int(math.Ceil(float64(report.steps)/float64(o.frames))))
+	whole := &hailstoneSeries{trajectory: report.trajectory, shift: absoluteShift(report.maxStoneInt), log: o.log}
+	yMax := whole.value(report.maxStoneInt)
+
+	reports := make(chan sequenceProgress)
+	go Collatz(*new(big.Int).Set(report.number), report.trajectory.mapping, reports, every)
+
+	// The run writes to the last word of the parity vector a snapshot shares,
+	// so the snapshots are charted once it has finished
+	var snapshots []*trajectory
+	for {
+		snapshot := <-reports
+		snapshots = append(snapshots, snapshot.trajectory)
+		if snapshot.lastStone {
+			break
+		}
+	}
+
+	var frames []*image.Paletted
+	for _, t := range snapshots {
+		frame, err := animationFrame(&hailstoneSeries{trajectory: t, shift: whole.shift, log: o.log}, float64(report.steps), yMax, o)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// animationFrame charts a trajectory on axes running to xMax and yMax
+func animationFrame(s *hailstoneSeries, xMax float64, yMax float64, o animationOptions) (*image.Paletted, error) {
+	xV, yV := s.Points(s.Bounds())
+	if len(xV) == 1 {
+		// go-chart needs two points to draw a line
+		xV, yV = append(xV, xV[0]), append(yV, yV[0])
+	}
+	graph := s.Chart(xV, yV)
+	graph.Width, graph.Height = o.width, o.height
+	graph.XAxis = chart.XAxis{Style: chart.Shown(), ValueFormatter: chart.IntValueFormatter, Range: &chart.ContinuousRange{Min: 0, Max: math.Max(xMax, 1)}}
+	graph.YAxis.Range = &chart.ContinuousRange{Min: 0, Max: math.Max(yMax, 1)}
+	if o.log {
+		graph.YAxis.Ticks = powerOfTwoTicks([]float64{0, yMax})
+	}
+
+	var buffer bytes.Buffer
+	if err := graph.Render(chart.PNG, &buffer); err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(&buffer)
+	if err != nil {
+		return nil, err
+	}
+	frame := image.NewPaletted(img.Bounds(), palette.Plan9)
+	draw.Draw(frame, frame.Rect, img, img.Bounds().Min, draw.Src)
+	return frame, nil
+}
+
+// writeAnimation writes the frames as a GIF that loops, holding the last frame
+func writeAnimation(w io.Writer, frames []*image.Paletted) error {
+	anim := &gif.GIF{Image: frames}
+	for i := range frames {
+		if i == len(frames)-1 {
+			anim.Delay = append(anim.Delay, animationLastFrameDelay)
+		} else {
+			anim.Delay = append(anim.Delay, animationFrameDelay)
+		}
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// exportAnimation saves an animation of a trajectory unfolding to the export folder
+func exportAnimation(report *sequenceProgress, o animationOptions, win fyne.Window) {
+	frames, err := animationFrames(report, o)
+	name := filepath.Join(currentSettings().ExportDir, fmt.Sprintf("collatz_%s.gif", abbreviateName(report.number)))
+	if err == nil {
+		var f *os.File
+		if f, err = os.Create(name); err == nil {
+			err = writeAnimation(f, frames)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	if err != nil {
+		showInformation("Export Error", fmt.Sprintf("The animation could not be saved: %v", err), win)
+		return
+	}
+	showInformation("Export", fmt.Sprintf("%d frames were saved to %s", len(frames), name), win)
+}
+
+// showAnimationDialog asks how the trajectory shown on the Single Value tab is animated and exports it
+func showAnimationDialog(report *sequenceProgress, win fyne.Window) {
+	if report == nil || !report.lastStone {
+		dialog.ShowInformation("Export Animation", "Calculate a value first", win)
+		return
+	}
+
+	intEntry := func(value int, lower int, upper int, name string) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetText(strconv.Itoa(value))
+		e.Validator = func(s string) error {
+			if v, err := strconv.Atoi(s); err != nil || v < lower || v > upper {
+				return fmt.Errorf("the %s must be between %d and %d", name, lower, upper)
+			}
+			return nil
+		}
+		return e
+	}
+	frames := intEntry(100, 1, maxAnimationFrames, "frame count")
+	width := intEntry(800, minAnimationSide, maxAnimationSide, "width")
+	height := intEntry(400, minAnimationSide, maxAnimationSide, "height")
+	axis := widget.NewSelect(animationAxes, nil)
+	axis.SetSelected(animationAxes[0])
+
+	dialog.ShowForm("Export Animation", "Export", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Frames", frames),
+		widget.NewFormItem("Width", width),
+		widget.NewFormItem("Height", height),
+		widget.NewFormItem("Axis", axis),
+	}, func(export bool) {
+		if !export {
+			return
+		}
+		o := animationOptions{log: axis.Selected == "Log"}
+		o.frames, _ = strconv.Atoi(frames.Text)
+		o.width, _ = strconv.Atoi(width.Text)
+		o.height, _ = strconv.Atoi(height.Text)
+		go exportAnimation(report, o, win)
+	}, win)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"image/gif"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestAnimationFrames(t *testing.T) {
+	report := Collatz(*big.NewInt(27), standardMap, nil, 0)
+
+	for _, log := range []bool{false, true} {
+		// 111 steps in ten frames is a report every 12 steps, nine of them, then the last
+		frames, err := animationFrames(&report, animationOptions{frames: 10, width: 300, height: 200, log: log})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(frames) != 10 {
+			t.Fatalf("%d frames, expected 10", len(frames))
+		}
+		for i, f := range frames {
+			if f.Bounds().Dx() != 300 || f.Bounds().Dy() != 200 {
+				t.Errorf("frame %d is %v, expected 300 × 200", i, f.Bounds())
+			}
+		}
+		if bytes.Equal(frames[0].Pix, frames[9].Pix) {
+			t.Errorf("the first and last frames are the same")
+		}
+	}
+
+	// 1 has no steps, so a single frame
+	one := Collatz(*big.NewInt(1), standardMap, nil, 0)
+	if frames, err := animationFrames(&one, animationOptions{frames: 10, width: 300, height: 200}); err != nil || len(frames) != 1 {
+		t.Errorf("%d frames for 1 (%v), expected 1", len(frames), err)
+	}
+}
+
+func TestExportAnimation(t *testing.T) {
+	a := test.NewApp()
+	defer a.Quit()
+
+	s := defaultSettings()
+	s.ExportDir = t.TempDir()
+	setSettings(s)
+	defer setSettings(defaultSettings())
+
+	report := Collatz(*big.NewInt(27), standardMap, nil, 0)
+	exportAnimation(&report, animationOptions{frames: 5, width: 320, height: 240}, a.NewWindow(""))
+	waitForUI()
+
+	f, err := os.Open(filepath.Join(s.ExportDir, "collatz_27.gif"))
+	if err != nil {
+		t.Fatalf("the animation was not saved: %v", err)
+	}
+	defer f.Close()
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 5 || anim.Config.Width != 320 || anim.Config.Height != 240 || anim.Delay[4] != animationLastFrameDelay {
+		t.Errorf("%d frames of %d × %d with delays %v, expected 5 of 320 × 240", len(anim.Image), anim.Config.Width, anim.Config.Height, anim.Delay)
+	}
+}
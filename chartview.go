@@ -0,0 +1,452 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+// chartSeries supplies the points of an interactive chart, which asks for them
+// again whenever it is zoomed or panned
+type chartSeries interface {
+	// Bounds returns the smallest and largest x
+	Bounds() (float64, float64)
+	// Points returns the points to plot with x between from and to
+	Points(from float64, to float64) (xV []float64, yV []float64)
+	// Nearest returns the point closest to x and the text shown when it is hovered
+	Nearest(x float64) (px float64, py float64, label string)
+	// Chart lays out a chart of the points
+	Chart(xV []float64, yV []float64) chart.Chart
+}
+
+// The colours drawn over the chart image, which is always on white
+var crosshairColor = color.NRGBA{R: 0x60, G: 0x60, B: 0x60, A: 0xff}
+var selectionColor = color.NRGBA{R: 0x2a, G: 0x6f, B: 0xdb, A: 0x40}
+var tooltipColor = color.NRGBA{R: 0xff, G: 0xff, B: 0xe8, A: 0xf0}
+
+// chartView shows a chart that is zoomed by dragging across it, panned by
+// scrolling and read by hovering. Double tapping shows the whole series again.
+type chartView struct {
+	widget.BaseWidget
+
+	// OnTapped is called with the x of the point nearest a tap
+	OnTapped func(x float64)
+
+	minSize fyne.Size
+
+	// The state below is changed from any goroutine, the canvas objects only by updateUI
+	lock      sync.Mutex
+	series    chartSeries
+	from, to  float64
+	rendering bool
+	dirty     bool
+	dragStart *fyne.Position
+	dragEnd   fyne.Position
+
+	// What the image shows: the series, its x and y ranges and where the
+	// plot is, in pixels of the image
+	shown              chartSeries
+	shownFrom, shownTo float64
+	yMin, yMax         float64
+	plot               chart.Box
+	imageWidth         int
+	imageHeight        int
+
+	image     *canvas.Image
+	selection *canvas.Rectangle
+	crossX    *canvas.Line
+	crossY    *canvas.Line
+	tipBox    *canvas.Rectangle
+	tipText   *canvas.Text
+}
+
+func newChartView(minSize fyne.Size) *chartView {
+	c := &chartView{
+		minSize:   minSize,
+		image:     &canvas.Image{},
+		selection: canvas.NewRectangle(selectionColor),
+		crossX:    canvas.NewLine(crosshairColor),
+		crossY:    canvas.NewLine(crosshairColor),
+		tipBox:    canvas.NewRectangle(tooltipColor),
+		tipText:   canvas.NewText("", color.Black),
+	}
+	c.tipBox.StrokeColor = crosshairColor
+	c.tipBox.StrokeWidth = 1
+	c.image.Hide()
+	c.selection.Hide()
+	c.hideCrosshair()
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// SetSeries shows the whole of a series
+func (c *chartView) SetSeries(s chartSeries) {
+	c.lock.Lock()
+	c.series = s
+	c.from, c.to = s.Bounds()
+	c.lock.Unlock()
+
+	c.render()
+}
+
+// Clear removes the chart
+func (c *chartView) Clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.series = nil
+	c.shown = nil
+	updateUI(func() {
+		c.image.Hide()
+		c.hideCrosshair()
+	})
+}
+
+// ZoomTo shows x from from to to, kept within the bounds of the series
+func (c *chartView) ZoomTo(from float64, to float64) {
+	c.lock.Lock()
+	if c.series == nil || to <= from {
+		c.lock.Unlock()
+		return
+	}
+	min, max := c.series.Bounds()
+	span := to - from
+	if span > max-min {
+		span = max - min
+	}
+	if from < min {
+		from = min
+	}
+	if from+span > max {
+		from = max - span
+	}
+	c.from, c.to = from, from+span
+	c.lock.Unlock()
+
+	c.render()
+}
+
+// ResetZoom shows the whole series again
+func (c *chartView) ResetZoom() {
+	c.lock.Lock()
+	s := c.series
+	c.lock.Unlock()
+
+	if s != nil {
+		c.ZoomTo(s.Bounds())
+	}
+}
+
+// shownRange returns the range of x the image shows
+func (c *chartView) shownRange() (float64, float64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.shownFrom, c.shownTo
+}
+
+// render draws the chart on a goroutine of its own. A change made while it is
+// drawing is drawn straight after, so the latest view is always shown.
+func (c *chartView) render() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.rendering {
+		c.dirty = true
+		return
+	}
+	c.rendering = true
+	go func() {
+		for c.renderOnce() {
+		}
+	}()
+}
+
+// renderOnce draws the current view and reports whether another is waiting
+func (c *chartView) renderOnce() bool {
+	c.lock.Lock()
+	series, from, to := c.series, c.from, c.to
+	c.dirty = false
+	c.lock.Unlock()
+
+	var graph chart.Chart
+	var buffer bytes.Buffer
+	var plot chart.Box
+	yRange := &chart.ContinuousRange{}
+	ok := false
+	if series != nil {
+		xV, yV := series.Points(from, to)
+		if len(xV) > 1 {
+			graph = series.Chart(xV, yV)
+			graph.XAxis.Range = &chart.ContinuousRange{Min: from, Max: to}
+			if r, isContinuous := graph.YAxis.Range.(*chart.ContinuousRange); isContinuous {
+				yRange = r
+			}
+			graph.YAxis.Range = yRange
+			graph.Elements = append(graph.Elements, func(r chart.Renderer, canvasBox chart.Box, defaults chart.Style) {
+				plot = canvasBox
+			})
+			ok = graph.Render(chart.PNG, &buffer) == nil
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.dirty || c.series != series {
+		return true
+	}
+	c.rendering = false
+	if !ok {
+		// Too few points to draw, so the view goes back to what is shown
+		if c.shown == series {
+			c.from, c.to = c.shownFrom, c.shownTo
+		}
+		return false
+	}
+
+	c.shown = series
+	c.shownFrom, c.shownTo = from, to
+	c.yMin, c.yMax = yRange.Min, yRange.Max
+	c.plot = plot
+	c.imageWidth, c.imageHeight = graph.GetWidth(), graph.GetHeight()
+
+	// Queued under the lock so a Clear cannot overtake it
+	res := fyne.NewStaticResource("chart.png", buffer.Bytes())
+	updateUI(func() {
+		c.image.Resource = res
+		c.image.Show()
+		c.image.Refresh()
+		c.hideCrosshair()
+	})
+	return false
+}
+
+// xAt returns the x under a position on the widget, which the lock must be held for
+func (c *chartView) xAt(pos fyne.Position) float64 {
+	px := float64(pos.X/c.Size().Width) * float64(c.imageWidth)
+	return c.shownFrom + (px-float64(c.plot.Left))/float64(c.plot.Width())*(c.shownTo-c.shownFrom)
+}
+
+// positionOf returns where a point is drawn on the widget, which the lock must be held for
+func (c *chartView) positionOf(x float64, y float64) fyne.Position {
+	px := float64(c.plot.Left) + (x-c.shownFrom)/(c.shownTo-c.shownFrom)*float64(c.plot.Width())
+	py := float64(c.plot.Bottom)
+	if c.yMax > c.yMin {
+		py -= (y - c.yMin) / (c.yMax - c.yMin) * float64(c.plot.Height())
+	}
+	return c.imagePosition(px, py)
+}
+
+// imagePosition converts pixels of the image, which is stretched over the widget, to a position
+func (c *chartView) imagePosition(px float64, py float64) fyne.Position {
+	size := c.Size()
+	return fyne.NewPos(float32(px)*size.Width/float32(c.imageWidth), float32(py)*size.Height/float32(c.imageHeight))
+}
+
+// plotCorners returns the top left and bottom right of the plot on the widget
+func (c *chartView) plotCorners() (fyne.Position, fyne.Position) {
+	return c.imagePosition(float64(c.plot.Left), float64(c.plot.Top)), c.imagePosition(float64(c.plot.Right), float64(c.plot.Bottom))
+}
+
+// nearest returns the shown point closest to a position and where it is drawn
+func (c *chartView) nearest(pos fyne.Position) (x float64, at fyne.Position, label string, ok bool) {
+	c.lock.Lock()
+	series := c.shown
+	if series == nil || c.Size().Width == 0 {
+		c.lock.Unlock()
+		return 0, at, "", false
+	}
+	x = c.xAt(pos)
+	if x < c.shownFrom {
+		x = c.shownFrom
+	}
+	if x > c.shownTo {
+		x = c.shownTo
+	}
+	c.lock.Unlock()
+
+	x, y, label := series.Nearest(x)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return x, c.positionOf(x, y), label, c.shown == series
+}
+
+func (c *chartView) hideCrosshair() {
+	c.crossX.Hide()
+	c.crossY.Hide()
+	c.tipBox.Hide()
+	c.tipText.Hide()
+}
+
+// MouseMoved puts the crosshair on the point nearest the mouse and shows its value
+func (c *chartView) MouseMoved(e *desktop.MouseEvent) {
+	_, at, label, ok := c.nearest(e.Position)
+	if !ok {
+		return
+	}
+	c.lock.Lock()
+	topLeft, bottomRight := c.plotCorners()
+	c.lock.Unlock()
+	size := c.Size()
+
+	updateUI(func() {
+		c.crossX.Position1 = fyne.NewPos(at.X, topLeft.Y)
+		c.crossX.Position2 = fyne.NewPos(at.X, bottomRight.Y)
+		c.crossY.Position1 = fyne.NewPos(topLeft.X, at.Y)
+		c.crossY.Position2 = fyne.NewPos(bottomRight.X, at.Y)
+
+		// The value sits beside the point, on whichever side has room
+		c.tipText.Text = label
+		textSize := fyne.MeasureText(label, c.tipText.TextSize, c.tipText.TextStyle)
+		boxSize := textSize.Add(fyne.NewSize(theme.Padding()*2, theme.Padding()*2))
+		boxPos := at.Add(fyne.NewPos(theme.Padding(), -boxSize.Height-theme.Padding()))
+		if boxPos.X+boxSize.Width > size.Width {
+			boxPos.X = at.X - boxSize.Width - theme.Padding()
+		}
+		if boxPos.Y < 0 {
+			boxPos.Y = at.Y + theme.Padding()
+		}
+		c.tipBox.Move(boxPos)
+		c.tipBox.Resize(boxSize)
+		c.tipText.Move(boxPos.Add(fyne.NewPos(theme.Padding(), theme.Padding())))
+		c.tipText.Resize(textSize)
+
+		for _, o := range []fyne.CanvasObject{c.crossX, c.crossY, c.tipBox, c.tipText} {
+			o.Show()
+			o.Refresh()
+		}
+	})
+}
+
+func (c *chartView) MouseIn(e *desktop.MouseEvent) {
+	c.MouseMoved(e)
+}
+
+func (c *chartView) MouseOut() {
+	updateUI(c.hideCrosshair)
+}
+
+// Cursor shows a crosshair over the chart
+func (c *chartView) Cursor() desktop.Cursor {
+	return desktop.CrosshairCursor
+}
+
+// Tapped hands the point nearest the tap to OnTapped
+func (c *chartView) Tapped(e *fyne.PointEvent) {
+	if c.OnTapped == nil {
+		return
+	}
+	if x, _, _, ok := c.nearest(e.Position); ok {
+		c.OnTapped(x)
+	}
+}
+
+func (c *chartView) DoubleTapped(*fyne.PointEvent) {
+	c.ResetZoom()
+}
+
+// Dragged shades the range of x that will be zoomed to
+func (c *chartView) Dragged(e *fyne.DragEvent) {
+	c.lock.Lock()
+	if c.shown == nil {
+		c.lock.Unlock()
+		return
+	}
+	if c.dragStart == nil {
+		start := e.Position.Subtract(e.Dragged)
+		c.dragStart = &start
+	}
+	c.dragEnd = e.Position
+	start := *c.dragStart
+	topLeft, bottomRight := c.plotCorners()
+	c.lock.Unlock()
+
+	left, right := start.X, e.Position.X
+	if right < left {
+		left, right = right, left
+	}
+	updateUI(func() {
+		c.selection.Move(fyne.NewPos(left, topLeft.Y))
+		c.selection.Resize(fyne.NewSize(right-left, bottomRight.Y-topLeft.Y))
+		c.selection.Show()
+		c.selection.Refresh()
+	})
+}
+
+// DragEnd zooms to the range dragged across
+func (c *chartView) DragEnd() {
+	c.lock.Lock()
+	if c.dragStart == nil {
+		c.lock.Unlock()
+		return
+	}
+	start, end := *c.dragStart, c.dragEnd
+	c.dragStart = nil
+	from, to := c.xAt(start), c.xAt(end)
+	c.lock.Unlock()
+
+	updateUI(c.selection.Hide)
+
+	// A short drag is taken as a slip rather than a zoom
+	if end.X-start.X < 4 && start.X-end.X < 4 {
+		return
+	}
+	if to < from {
+		from, to = to, from
+	}
+	c.ZoomTo(from, to)
+}
+
+// Scrolled pans along x by the distance scrolled
+func (c *chartView) Scrolled(e *fyne.ScrollEvent) {
+	c.lock.Lock()
+	if c.shown == nil {
+		c.lock.Unlock()
+		return
+	}
+	topLeft, bottomRight := c.plotCorners()
+	shift := -float64((e.Scrolled.DX+e.Scrolled.DY)/(bottomRight.X-topLeft.X)) * (c.to - c.from)
+	from, to := c.from+shift, c.to+shift
+	c.lock.Unlock()
+
+	c.ZoomTo(from, to)
+}
+
+func (c *chartView) CreateRenderer() fyne.WidgetRenderer {
+	return &chartViewRenderer{view: c, objects: []fyne.CanvasObject{c.image, c.selection, c.crossX, c.crossY, c.tipBox, c.tipText}}
+}
+
+type chartViewRenderer struct {
+	view    *chartView
+	objects []fyne.CanvasObject
+}
+
+func (r *chartViewRenderer) Layout(size fyne.Size) {
+	r.view.image.Resize(size)
+}
+
+func (r *chartViewRenderer) MinSize() fyne.Size {
+	return r.view.minSize
+}
+
+func (r *chartViewRenderer) Refresh() {
+	for _, o := range r.objects {
+		o.Refresh()
+	}
+}
+
+func (r *chartViewRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *chartViewRenderer) Destroy() {
+}
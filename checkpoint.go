@@ -0,0 +1,357 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+const checkpointFile = "checkpoint.json"
+
+// How often a running sweep writes its checkpoint
+var checkpointInterval = 10 * time.Second
+
+type sweepRecord struct {
+	Number   string `json:"number"`
+	Steps    int    `json:"steps"`
+	MaxStone string `json:"maxStone"`
+}
+
+// sweepCheckpoint is the state of a range sweep up to and including the last
+// contiguous completed value. Everything in it is in decimal. A sweep runs
+// from Lower in steps of Stride, through Values in order, or through the
+// SampleSize values drawn with Seed from Lower. Upper is one past the last value. A search keeps its predicate, its limit and the values
+// completed so far that match it.
+type sweepCheckpoint struct {
+	Lower                string        `json:"lower"`
+	Upper                string        `json:"upper"`
+	Stride               string        `json:"stride,omitempty"`
+	Values               []string      `json:"values,omitempty"`
+	SampleSize           int64         `json:"sampleSize,omitempty"`
+	Seed                 int64         `json:"seed,omitempty"`
+	Map                  string        `json:"map,omitempty"`
+	Search               string        `json:"search,omitempty"`
+	SearchLimit          int           `json:"searchLimit,omitempty"`
+	Matches              []string      `json:"matches,omitempty"`
+	LastCompleted        string        `json:"lastCompleted"`
+	Completed            int64         `json:"completed"`
+	Finished             bool          `json:"finished"`
+	HighwaterSteps       int           `json:"highwaterSteps"`
+	HighwaterStepsNumber string        `json:"highwaterStepsNumber"`
+	HighwaterStone       string        `json:"highwaterStone"`
+	HighwaterStoneNumber string        `json:"highwaterStoneNumber"`
+	StepRecords          []sweepRecord `json:"stepRecords"`
+	StoneRecords         []sweepRecord `json:"stoneRecords"`
+	Histogram            map[int]int64 `json:"histogram"`
+	Saved                time.Time     `json:"saved"`
+}
+
+// sweepTracker follows the completion of a range sweep. The workers finish out
+// of order, so each result is held back until every value before it has
+// completed and is then folded, in order, into the checkpoint state. Values
+// are counted by their offset, the number of values before them in the sweep.
+type sweepTracker struct {
+	lower    *big.Int
+	stride   *big.Int
+	values   []*big.Int
+	offsets  map[string]int64
+	total    int64
+	maxStone *big.Int
+	pending  map[int64]sequenceProgress
+	lastSave time.Time
+	state    sweepCheckpoint
+}
+
+// newSweepTracker follows every value from lower up to but not including upper
+func newSweepTracker(lower *big.Int, upper *big.Int) (*sweepTracker, error) {
+	return newStrideTracker(lower, upper, oneBig)
+}
+
+// newStrideTracker follows lower, lower+stride, lower+2*stride and so on below
+// upper, which is not run. Callers whose upper limit is included pass it plus
+// one. The values are counted in 64 bits, so a sweep of more is refused.
+func newStrideTracker(lower *big.Int, upper *big.Int, stride *big.Int) (*sweepTracker, error) {
+	total := big.NewInt(0)
+	if upper.Cmp(lower) > 0 {
+		total.Sub(upper, lower)
+		total.Add(total, stride)
+		total.Sub(total, oneBig)
+		total.Quo(total, stride)
+	}
+	if !total.IsInt64() {
+		return nil, fmt.Errorf("the sweep has %s values, more than the %d that can be run", total, int64(math.MaxInt64))
+	}
+	t := &sweepTracker{
+		lower:    new(big.Int).Set(lower),
+		stride:   new(big.Int).Set(stride),
+		total:    total.Int64(),
+		maxStone: big.NewInt(0),
+		pending:  make(map[int64]sequenceProgress),
+		state: sweepCheckpoint{
+			Lower:     lower.String(),
+			Upper:     upper.String(),
+			Histogram: make(map[int]int64),
+		},
+	}
+	if stride.Cmp(oneBig) != 0 {
+		t.state.Stride = stride.String()
+	}
+	return t, nil
+}
+
+// newListTracker follows a list of distinct values in the order given
+func newListTracker(values []*big.Int) *sweepTracker {
+	t := &sweepTracker{
+		offsets:  make(map[string]int64, len(values)),
+		total:    int64(len(values)),
+		maxStone: big.NewInt(0),
+		pending:  make(map[int64]sequenceProgress),
+		state:    sweepCheckpoint{Histogram: make(map[int]int64)},
+	}
+	for i, v := range values {
+		t.values = append(t.values, new(big.Int).Set(v))
+		t.offsets[v.String()] = int64(i)
+		t.state.Values = append(t.state.Values, v.String())
+	}
+	return t
+}
+
+// resumeSweepTracker picks up a sweep from a saved checkpoint. The returned
+// tracker expects the next result to be the value after LastCompleted.
+func resumeSweepTracker(cp sweepCheckpoint) (*sweepTracker, error) {
+	var t *sweepTracker
+	if cp.SampleSize > 0 {
+		lower, ok := new(big.Int).SetString(cp.Lower, 10)
+		if !ok {
+			return nil, errors.New("checkpoint has an invalid lower limit")
+		}
+		upper, ok := new(big.Int).SetString(cp.Upper, 10)
+		if !ok {
+			return nil, errors.New("checkpoint has an invalid upper limit")
+		}
+		var err error
+		if t, err = newSampleTracker(lower, upper.Sub(upper, oneBig), cp.SampleSize, cp.Seed); err != nil {
+			return nil, err
+		}
+	} else if cp.Values != nil {
+		var values []*big.Int
+		for _, s := range cp.Values {
+			v, ok := new(big.Int).SetString(s, 10)
+			if !ok {
+				return nil, errors.New("checkpoint has an invalid value in its list")
+			}
+			values = append(values, v)
+		}
+		t = newListTracker(values)
+	} else {
+		lower, ok := new(big.Int).SetString(cp.Lower, 10)
+		if !ok {
+			return nil, errors.New("checkpoint has an invalid lower limit")
+		}
+		upper, ok := new(big.Int).SetString(cp.Upper, 10)
+		if !ok {
+			return nil, errors.New("checkpoint has an invalid upper limit")
+		}
+		stride := big.NewInt(1)
+		if cp.Stride != "" {
+			if _, ok := stride.SetString(cp.Stride, 10); !ok || stride.Sign() <= 0 {
+				return nil, errors.New("checkpoint has an invalid stride")
+			}
+		}
+		var err error
+		if t, err = newStrideTracker(lower, upper, stride); err != nil {
+			return nil, err
+		}
+	}
+	if cp.Completed < 0 || cp.Completed > t.total {
+		return nil, fmt.Errorf("checkpoint has %d values completed of the %d in the sweep", cp.Completed, t.total)
+	}
+	t.state = cp
+	if t.state.Histogram == nil {
+		t.state.Histogram = make(map[int]int64)
+	}
+	if cp.HighwaterStone != "" {
+		if _, ok := t.maxStone.SetString(cp.HighwaterStone, 10); !ok {
+			return nil, errors.New("checkpoint has an invalid high water stone")
+		}
+	}
+	return t, nil
+}
+
+// lastValue returns the last value the sweep runs, in decimal. A sweep with no
+// values gives the included upper limit, one less than Upper, as does a sample,
+// which is not drawn again to find it.
+func (cp sweepCheckpoint) lastValue() string {
+	if cp.SampleSize == 0 {
+		if t, err := resumeSweepTracker(cp); err == nil && t.total > 0 {
+			return t.value(t.total - 1).String()
+		}
+	}
+	if upper, ok := new(big.Int).SetString(cp.Upper, 10); ok {
+		return upper.Sub(upper, oneBig).String()
+	}
+	return cp.Upper
+}
+
+// describe names the values of the sweep for a message
+func (cp sweepCheckpoint) describe() string {
+	if cp.SampleSize > 0 {
+		return fmt.Sprintf("of %d values drawn from %s to %s with the seed %d", cp.SampleSize, cp.Lower, cp.lastValue(), cp.Seed)
+	}
+	if cp.Values != nil {
+		return fmt.Sprintf("of %d listed values", len(cp.Values))
+	}
+	if cp.Stride != "" {
+		return fmt.Sprintf("from %s to %s in steps of %s", cp.Lower, cp.lastValue(), cp.Stride)
+	}
+	return fmt.Sprintf("from %s to %s", cp.Lower, cp.lastValue())
+}
+
+// bounds returns the smallest and largest values of a sweep that has any
+func (t *sweepTracker) bounds() (*big.Int, *big.Int) {
+	if t.values == nil {
+		return new(big.Int).Set(t.lower), t.value(t.total - 1)
+	}
+	low, high := t.values[0], t.values[0]
+	for _, v := range t.values {
+		if v.Cmp(low) < 0 {
+			low = v
+		}
+		if v.Cmp(high) > 0 {
+			high = v
+		}
+	}
+	return new(big.Int).Set(low), new(big.Int).Set(high)
+}
+
+// value returns a copy of the value at an offset
+func (t *sweepTracker) value(offset int64) *big.Int {
+	if t.values != nil {
+		return new(big.Int).Set(t.values[offset])
+	}
+	v := new(big.Int).Mul(t.stride, big.NewInt(offset))
+	return v.Add(v, t.lower)
+}
+
+// offset returns the number of values before n in the sweep, and false if n is not in it
+func (t *sweepTracker) offset(n *big.Int) (int64, bool) {
+	if t.values != nil {
+		offset, ok := t.offsets[n.String()]
+		return offset, ok
+	}
+	offset, rem := new(big.Int).QuoRem(new(big.Int).Sub(n, t.lower), t.stride, new(big.Int))
+	if offset.Sign() < 0 || rem.Sign() != 0 || !offset.IsInt64() || offset.Int64() >= t.total {
+		return 0, false
+	}
+	return offset.Int64(), true
+}
+
+// add holds back a result until every value before it has completed. A result
+// for a value that is not in the sweep is dropped and add returns false.
+func (t *sweepTracker) add(report sequenceProgress) bool {
+	offset, ok := t.offset(report.number)
+	if !ok {
+		return false
+	}
+	t.pending[offset] = report
+
+	for {
+		r, ok := t.pending[t.state.Completed]
+		if !ok {
+			break
+		}
+		delete(t.pending, t.state.Completed)
+		t.fold(r)
+	}
+	return true
+}
+
+func (t *sweepTracker) fold(r sequenceProgress) {
+	s := &t.state
+	s.Completed++
+	s.LastCompleted = r.number.String()
+	s.Finished = s.Completed >= t.total
+	s.Histogram[r.steps]++
+	if r.matched {
+		s.Matches = append(s.Matches, r.number.String())
+	}
+
+	if r.steps > s.HighwaterSteps || s.HighwaterStepsNumber == "" {
+		s.HighwaterSteps = r.steps
+		s.HighwaterStepsNumber = r.number.String()
+		s.StepRecords = append(s.StepRecords, sweepRecord{Number: r.number.String(), Steps: r.steps, MaxStone: r.maxStoneInt.String()})
+	}
+	if r.maxStoneInt.Cmp(t.maxStone) == 1 {
+		t.maxStone = new(big.Int).Set(r.maxStoneInt)
+		s.HighwaterStone = t.maxStone.String()
+		s.HighwaterStoneNumber = r.number.String()
+		s.StoneRecords = append(s.StoneRecords, sweepRecord{Number: r.number.String(), Steps: r.steps, MaxStone: r.maxStoneInt.String()})
+	}
+}
+
+// checkpoint writes the state to disk if the checkpoint interval has passed or force is set
+func (t *sweepTracker) checkpoint(force bool) error {
+	if !force && time.Since(t.lastSave) < checkpointInterval {
+		return nil
+	}
+	t.lastSave = time.Now()
+	t.state.Saved = t.lastSave
+	return saveCheckpoint(t.state)
+}
+
+func saveCheckpoint(cp sweepCheckpoint) error {
+	path, err := storagePath(checkpointFile)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it so a crash never leaves half a checkpoint
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func loadCheckpoint() (cp sweepCheckpoint, err error) {
+	path, err := storagePath(checkpointFile)
+	if err != nil {
+		return cp, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(data, &cp)
+	return cp, err
+}
+
+// storagePath returns the location of a file in the application's storage
+// directory, falling back to the user config directory when there is no app.
+func storagePath(name string) (string, error) {
+	var dir string
+	if a := fyne.CurrentApp(); a != nil && a.Storage() != nil {
+		dir = a.Storage().RootURI().Path()
+	} else {
+		config, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(config, "collatzfyne")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
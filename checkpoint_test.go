@@ -0,0 +1,176 @@
+package main
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+// runTracker folds a report for each value of a tracker into it, last to first
+func runTracker(t *testing.T, tracker *sweepTracker) []int64 {
+	t.Helper()
+
+	var values []int64
+	for offset := tracker.total - 1; offset >= 0; offset-- {
+		n := tracker.value(offset)
+		values = append([]int64{n.Int64()}, values...)
+		tracker.add(CollatzPerf(*n, standardMap))
+		if tracker.state.Completed != 0 && offset != 0 {
+			t.Fatalf("%d values completed while %s is still pending", tracker.state.Completed, tracker.value(0))
+		}
+	}
+	return values
+}
+
+func TestStrideTracker(t *testing.T) {
+	// n ≡ 27 (mod 64) from 27 below 300
+	tracker, err := newStrideTracker(big.NewInt(27), big.NewInt(300), big.NewInt(64))
+	if err != nil || tracker.total != 5 || tracker.state.Stride != "64" {
+		t.Fatalf("%d values with a stride of %q, expected 5 with a stride of 64", tracker.total, tracker.state.Stride)
+	}
+	values := runTracker(t, tracker)
+	if len(values) != 5 || values[0] != 27 || values[4] != 283 {
+		t.Errorf("the values are %v, expected 27 to 283 in steps of 64", values)
+	}
+	if !tracker.state.Finished || tracker.state.LastCompleted != "283" || tracker.state.HighwaterStepsNumber != "27" {
+		t.Errorf("the state is %+v, expected finished at 283 with the longest at 27", tracker.state)
+	}
+
+	// A resumed tracker runs the same values and names them for the Range tab
+	cp := tracker.state
+	cp.Completed, cp.Finished = 2, false
+	resumed, err := resumeSweepTracker(cp)
+	if err != nil || resumed.total != 5 || resumed.value(cp.Completed).Int64() != 155 {
+		t.Errorf("the resumed tracker has %d values, next %v (%v), expected 5, next 155", resumed.total, resumed.value(cp.Completed), err)
+	}
+	if d := cp.describe(); d != "from 27 to 283 in steps of 64" {
+		t.Errorf("the checkpoint is described as %q", d)
+	}
+	for _, n := range []int64{26, 28, 347} {
+		if _, ok := resumed.offset(big.NewInt(n)); ok {
+			t.Errorf("%d was found in the sweep from 27 to 283 in steps of 64", n)
+		}
+	}
+
+	// Without a stride every value below the upper limit is run
+	if tracker, _ := newSweepTracker(big.NewInt(5), big.NewInt(9)); tracker.total != 4 || tracker.state.Stride != "" || tracker.state.describe() != "from 5 to 8" {
+		t.Errorf("%d values %s, expected 4 from 5 to 8", tracker.total, tracker.state.describe())
+	}
+
+	// Upper is one past the last value, which is the limit a sweep of none names
+	if cp := (sweepCheckpoint{Lower: "10", Upper: "10"}); cp.lastValue() != "9" {
+		t.Errorf("the last value of an empty sweep is %s, expected 9", cp.lastValue())
+	}
+
+	// A sweep of more values than can be counted is refused, not truncated
+	huge := new(big.Int).Lsh(oneBig, 64)
+	if _, err := newSweepTracker(oneBig, huge); err == nil {
+		t.Errorf("a sweep of 2^64 values was accepted")
+	}
+	if _, err := newStrideTracker(oneBig, huge, big.NewInt(4)); err != nil {
+		t.Errorf("a sweep of 2^62 values in steps of 4 was refused: %v", err)
+	}
+	cp = sweepCheckpoint{Lower: "1", Upper: huge.String()}
+	if _, err := resumeSweepTracker(cp); err == nil {
+		t.Errorf("a checkpoint of 2^64 values was resumed")
+	}
+}
+
+func TestListTracker(t *testing.T) {
+	tracker := newListTracker([]*big.Int{big.NewInt(97), big.NewInt(27), big.NewInt(871)})
+	values := runTracker(t, tracker)
+	if len(values) != 3 || values[0] != 97 || values[1] != 27 || values[2] != 871 {
+		t.Errorf("the values are %v, expected 97, 27 and 871", values)
+	}
+	if !tracker.state.Finished || tracker.state.LastCompleted != "871" || tracker.state.HighwaterStepsNumber != "871" {
+		t.Errorf("the state is %+v, expected finished at 871 with the longest at 871", tracker.state)
+	}
+
+	cp := tracker.state
+	cp.Completed, cp.Finished = 1, false
+	resumed, err := resumeSweepTracker(cp)
+	if offset, ok := resumed.offset(big.NewInt(871)); err != nil || resumed.total != 3 || resumed.value(1).Int64() != 27 || !ok || offset != 2 {
+		t.Errorf("the resumed tracker has %d values (%v), expected the same list", resumed.total, err)
+	}
+
+	// A value that is not in the list is dropped, not taken for the first
+	if resumed.add(CollatzPerf(*big.NewInt(5), standardMap)) || len(resumed.pending) != 0 {
+		t.Errorf("5 was added to a sweep of 97, 27 and 871")
+	}
+	if d := cp.describe(); d != "of 3 listed values" {
+		t.Errorf("the checkpoint is described as %q", d)
+	}
+}
+
+func TestCheckpointResumesMidSweep(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	a := test.NewApp()
+	defer a.Quit()
+
+	// A sweep runs without a break
+	whole, err := newSweepTracker(big.NewInt(1), big.NewInt(1001))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for offset := int64(0); offset < whole.total; offset++ {
+		whole.add(CollatzPerf(*whole.value(offset), standardMap))
+	}
+
+	// The same sweep stops with 600 values in order and a few after them
+	// pending, and is saved and loaded
+	first, _ := newSweepTracker(big.NewInt(1), big.NewInt(1001))
+	first.state.Map = standardMap.String()
+	for _, offset := range []int64{650, 700} {
+		first.add(CollatzPerf(*first.value(offset), standardMap))
+	}
+	for offset := int64(0); offset < 600; offset++ {
+		first.add(CollatzPerf(*first.value(offset), standardMap))
+	}
+	if err := first.checkpoint(true); err != nil {
+		t.Fatal(err)
+	}
+	cp, err := loadCheckpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Completed != 600 || cp.LastCompleted != "600" || cp.Finished || cp.Map != "standard" {
+		t.Errorf("the checkpoint has %d values completed to %s, expected 600 of the standard map", cp.Completed, cp.LastCompleted)
+	}
+
+	// It picks up after the last value in order and ends as the whole sweep did
+	resumed, err := resumeSweepTracker(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for offset := cp.Completed; offset < resumed.total; offset++ {
+		resumed.add(CollatzPerf(*resumed.value(offset), standardMap))
+	}
+	got, want := resumed.state, whole.state
+	got.Saved, got.Map = want.Saved, want.Map
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("the resumed sweep ends as %+v, expected %+v", got, want)
+	}
+}
+
+func TestResumeInvalidCheckpoint(t *testing.T) {
+	for _, cp := range []sweepCheckpoint{
+		{Lower: "1", Upper: "11", Completed: -1},
+		{Lower: "1", Upper: "11", Completed: 11},
+		{Lower: "1", Upper: "11", Stride: "0"},
+		{Lower: "one", Upper: "11"},
+		{Values: []string{"27", "x"}},
+		{Values: []string{"27", "97"}, Completed: 3},
+		{Lower: "1", Upper: "11", HighwaterStone: "lots"},
+	} {
+		if _, err := resumeSweepTracker(cp); err == nil {
+			t.Errorf("the checkpoint %+v was resumed", cp)
+		}
+	}
+
+	// Every value completed is a finished sweep
+	if _, err := resumeSweepTracker(sweepCheckpoint{Lower: "1", Upper: "11", Completed: 10, Finished: true}); err != nil {
+		t.Errorf("a finished checkpoint was not resumed: %v", err)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// runCommand runs the command line interface and returns the process exit code
+func runCommand(args []string, out io.Writer) int {
+	switch args[0] {
+	case "db":
+		return runDatabaseCommand(args[1:], out)
+	case "serve":
+		return runServeCommand(args[1:], out)
+	case "coordinate":
+		return runCoordinateCommand(args[1:], out)
+	case "work":
+		return runWorkCommand(args[1:], out)
+	case "help", "-h", "-help", "--help":
+		printUsage(out)
+		return 0
+	}
+	fmt.Fprintf(out, "Unknown command %q\n\n", args[0])
+	printUsage(out)
+	return 2
+}
+
+func printUsage(out io.Writer) {
+	fmt.Fprintln(out, "Usage:")
+	fmt.Fprintln(out, "  collatzfyne                 start the visualiser")
+	fmt.Fprintln(out, "  collatzfyne db get <n>      show the stored result for n")
+	fmt.Fprintln(out, "  collatzfyne db query        list stored results, see db query -h")
+	fmt.Fprintln(out, "  collatzfyne db stats        show the size of the results database")
+	fmt.Fprintln(out, "  collatzfyne serve           serve results as JSON over HTTP, see serve -h")
+	fmt.Fprintln(out, "  collatzfyne coordinate      lease blocks of a range to remote workers, see coordinate -h")
+	fmt.Fprintln(out, "  collatzfyne work            compute blocks leased from a coordinator, see work -h")
+}
+
+func runServeCommand(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(out)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	workers := flags.Int("workers", workerCount, "number of workers in the pool")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	startWorkerPool(*workers)
+
+	store, err := openDefaultResultStore(false)
+	if err != nil {
+		fmt.Fprintf(out, "Unable to open the results database: %v\n", err)
+	} else {
+		results = store
+		defer results.Close()
+	}
+
+	fmt.Fprintf(out, "Serving on http://%s\n", *addr)
+	if err := http.ListenAndServe(*addr, newAPIServer().Handler()); err != nil {
+		fmt.Fprintf(out, "Server stopped: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runDatabaseCommand(args []string, out io.Writer) int {
+	if len(args) == 0 {
+		printUsage(out)
+		return 2
+	}
+
+	store, err := openDefaultResultStore(true)
+	if err != nil {
+		fmt.Fprintf(out, "Unable to open the results database: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	switch args[0] {
+	case "get":
+		flags := flag.NewFlagSet("db get", flag.ContinueOnError)
+		flags.SetOutput(out)
+		base := flags.Int("base", 10, "base of the value")
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 1 {
+			fmt.Fprintln(out, "Usage: collatzfyne db get [-base b] <n>")
+			return 2
+		}
+		n, ok := new(big.Int).SetString(flags.Arg(0), *base)
+		if !ok {
+			fmt.Fprintf(out, "The entry %s is not a valid input for Base %d\n", flags.Arg(0), *base)
+			return 2
+		}
+		e, ok := store.Get(n)
+		if !ok {
+			fmt.Fprintf(out, "%s is not in the results database\n", n.String())
+			return 1
+		}
+		printStoreEntries(out, []storeEntry{e})
+		return 0
+
+	case "query":
+		var q storeQuery
+		flags := flag.NewFlagSet("db query", flag.ContinueOnError)
+		flags.SetOutput(out)
+		flags.IntVar(&q.MinSteps, "min-steps", 0, "smallest total stopping time")
+		flags.IntVar(&q.MaxSteps, "max-steps", 0, "largest total stopping time")
+		flags.IntVar(&q.MinStoppingTime, "min-stopping", 0, "smallest stopping time")
+		flags.IntVar(&q.MaxStoppingTime, "max-stopping", 0, "largest stopping time")
+		flags.StringVar(&q.ParityHash, "parity", "", "parity vector hash")
+		flags.IntVar(&q.Limit, "limit", 100, "maximum number of results, 0 for all")
+		if err := flags.Parse(args[1:]); err != nil {
+			return 2
+		}
+		entries, err := store.Query(q)
+		if err != nil {
+			fmt.Fprintf(out, "Unable to query the results database: %v\n", err)
+			return 1
+		}
+		printStoreEntries(out, entries)
+		return 0
+
+	case "stats":
+		fmt.Fprintf(out, "%d values stored\n", store.Len())
+		return 0
+	}
+
+	fmt.Fprintf(out, "Unknown database command %q\n", args[0])
+	return 2
+}
+
+func printStoreEntries(out io.Writer, entries []storeEntry) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "Number\tSteps\tStopping Time\tMax Stone\tParity Hash")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", e.Number, e.Steps, e.StoppingTime, e.MaxStone, e.ParityHash)
+	}
+	w.Flush()
+}
+
+func runCoordinateCommand(args []string, out io.Writer) int {
+	flags := flag.NewFlagSet("coordinate", flag.ContinueOnError)
+	flags.SetOutput(out)
+	listen := flags.String("listen", ":7070", "address to accept workers on")
+	lowerText := flags.String("lower", "1", "lower limit of the range")
+	upperText := flags.String("upper", "", "upper limit of the range, which is included")
+	base := flags.Int("base", 10, "base of the limits")
+	mapName := flags.String("map", "standard", "map to iterate, standard or shortcut")
+	blockSize := flags.Int64("block", defaultBlockSize, "number of values in each lease")
+	leaseTimeout := flags.Duration("lease", defaultLeaseTimeout, "time after which an unreported lease is reassigned")
+	metrics := flags.String("metrics", metricsAddr, "address to serve metrics on, none if empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	lower, err := parseStartValue(*lowerText, *base)
+	if err != nil {
+		fmt.Fprintf(out, "Lower limit: %v\n", err)
+		return 2
+	}
+	upper, err := parseStartValue(*upperText, *base)
+	if err != nil {
+		fmt.Fprintf(out, "Upper limit: %v\n", err)
+		return 2
+	}
+	mapping, err := parseCollatzMap(*mapName)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+
+	coord, err := newCoordinator(lower, new(big.Int).Add(upper, oneBig), mapping, *blockSize, *leaseTimeout)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+	addr, err := coord.Listen(*listen)
+	if err != nil {
+		fmt.Fprintf(out, "Unable to listen on %s: %v\n", *listen, err)
+		return 1
+	}
+	defer coord.Close()
+	fmt.Fprintf(out, "Coordinating [%s, %s] on %s\n", lower.String(), upper.String(), addr.String())
+	startMetrics(*metrics, out)
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-coord.Done():
+			_, summary, _ := coord.Progress()
+			printBlockSummary(out, summary)
+			return 0
+		case <-ticker.C:
+			fraction, summary, workers := coord.Progress()
+			fmt.Fprintf(out, "%6.2f%% done, %d workers, max steps %d for %s\n", fraction*100, len(workers), summary.HighwaterSteps, summary.HighwaterStepsNumber)
+		}
+	}
+}
+
+func runWorkCommand(args []string, out io.Writer) int {
+	host, _ := os.Hostname()
+
+	flags := flag.NewFlagSet("work", flag.ContinueOnError)
+	flags.SetOutput(out)
+	addr := flags.String("coordinator", "localhost:7070", "address of the coordinator")
+	name := flags.String("name", fmt.Sprintf("%s-%d", host, os.Getpid()), "name reported to the coordinator")
+	workers := flags.Int("workers", workerCount, "number of workers in the pool")
+	metrics := flags.String("metrics", metricsAddr, "address to serve metrics on, none if empty")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	startWorkerPool(*workers)
+	startMetrics(*metrics, out)
+
+	fmt.Fprintf(out, "Working for %s as %s\n", *addr, *name)
+	if err := runRemoteWorker(*addr, *name, nil); err != nil {
+		fmt.Fprintf(out, "Worker stopped: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(out, "The sweep is complete")
+	return 0
+}
+
+func printBlockSummary(out io.Writer, summary BlockSummary) {
+	fmt.Fprintf(out, "Values:                      %d\n", summary.Count)
+	fmt.Fprintf(out, "Max Sequence Length:         %d\n", summary.HighwaterSteps)
+	fmt.Fprintf(out, "Max Sequence Length Number:  %s\n", summary.HighwaterStepsNumber)
+	fmt.Fprintf(out, "Max Stone:                   %s\n", summary.HighwaterStone)
+	fmt.Fprintf(out, "Max Stone Number:            %s\n", summary.HighwaterStoneNumber)
+}
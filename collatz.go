@@ -1,98 +1,104 @@
 package main
 
 import (
-	"math"
+	"fmt"
 	"math/big"
 )
 
-func Collatz(n big.Int, reportChannel chan sequenceProgress, reportFrequency int) (report sequenceProgress) {
+// collatzMap selects the function that is iterated
+type collatzMap int
+
+const (
+	standardMap collatzMap = iota // n/2 for even n, 3n+1 for odd n
+	shortcutMap                   // n/2 for even n, (3n+1)/2 for odd n
+)
+
+var collatzMapNames = []string{"standard", "shortcut"}
+
+func (m collatzMap) String() string {
+	return collatzMapNames[m]
+}
+
+func parseCollatzMap(s string) (collatzMap, error) {
+	if s == "" {
+		return standardMap, nil
+	}
+	for idx, name := range collatzMapNames {
+		if s == name {
+			return collatzMap(idx), nil
+		}
+	}
+	return standardMap, fmt.Errorf("unknown map %q, expected one of %v", s, collatzMapNames)
+}
+
+// oddStep applies the map to an odd stone in place
+func (m collatzMap) oddStep(n *big.Int) {
+	n.Mul(n, threeBig)
+	n.Add(n, oneBig)
+	if m == shortcutMap {
+		n.Rsh(n, 1)
+	}
+}
+
+// Collatz runs n to 1, keeping the trajectory. When reportChannel is set a
+// report of the trajectory so far is sent on it every reportFrequency steps.
+func Collatz(n big.Int, m collatzMap, reportChannel chan sequenceProgress, reportFrequency int) (report sequenceProgress) {
 
 	steps := 0                        // Number of steps taken to reach 1
 	up := 0                           // Number of times the number was multiplied by 3 and added 1
 	down := 0                         // Number of times the number was divided by 2
-	stonesSlice := make([]big.Int, 0) // Slice to store the stones in the sequence
+	stoppingTime := 0                 // Number of steps taken to first fall below the original number
+	parityHash := uint64(fnvOffset64) // Hash of the parity vector of the sequence
 	maxStone := new(big.Int).Set(&n)  // Maximum stone in the sequence
 	number := new(big.Int).Set(&n)    // Original number
+	stones := newTrajectory(&n, m)    // The parity vector the stones are regenerated from
 
-	var maxStoneFloat *big.Float
-	var stonesScaled []float64
-	var stonesF64 []float64
-	var stonesString []string
-	var upDirection []bool
-
-	stonesSlice = append(stonesSlice, *new(big.Int).Set(&n))
 	//loop until n is equal to 1
 	for n.Cmp(oneBig) != 0 {
-		if new(big.Int).Mod(&n, twoBig).Cmp(zeroBig) == 0 {
-			n.Div(&n, twoBig)
-			down++
-		} else {
-			n.Mul(&n, threeBig)
-			n.Add(&n, oneBig)
+		odd := n.Bit(0) == 1
+		if odd {
+			m.oddStep(&n)
 			up++
+			parityHash = hashParity(parityHash, 1)
+		} else {
+			n.Rsh(&n, 1)
+			down++
+			parityHash = hashParity(parityHash, 0)
 		}
 		steps++
+		stones.record(odd, &n)
+
+		if stoppingTime == 0 && n.Cmp(number) == -1 {
+			stoppingTime = steps
+		}
 
 		// If the current stone is greater than the maximum stone, update the maximum stone
 		if n.Cmp(maxStone) == 1 {
 			maxStone = new(big.Int).Set(&n)
 		}
 
-		// Append the current stone to the slice
-		stonesSlice = append(stonesSlice, *new(big.Int).Set(&n))
-
-		// If the number of steps is a multiple of the report frequency or the current stone is equal to 1, send a report
-		if steps%reportFrequency == 0 || n.Cmp(oneBig) == 0 {
-
-			maxStoneFloat = new(big.Float).SetInt(maxStone)
-			stonesScaled = make([]float64, len(stonesSlice))
-			stonesF64 = make([]float64, len(stonesSlice))
-			stonesString = make([]string, len(stonesSlice))
-			upDirection = make([]bool, len(stonesSlice))
-
-			for idx, s := range stonesSlice {
-
-				// Divide each stone by the maximum stone and convert it to a float64
-				a, _ := new(big.Float).Quo(new(big.Float).SetInt(&s), maxStoneFloat).Float64()
-				stonesScaled[idx] = a
-
-				// Check if the current stone is greater than the previous stone
-				if idx > 0 {
-					upDirection[idx] = stonesSlice[idx].Cmp(&stonesSlice[idx-1]) == 1
-				} else {
-					upDirection[idx] = false
-				}
-
-				// Convert the stone to a float64
-				sf64, err := bigIntToFloat64(&s)
-
-				// If the conversion fails, set the float64 to the maximum float64 value
-				if err != nil {
-					sf64 = math.MaxFloat64
-				}
-				// Store the float64 and string representation of the stone
-				stonesF64[idx] = sf64
-				stonesString[idx] = s.String()
-			}
-			if reportChannel != nil {
-
-				// Create a new record and send it to the report channel
-				report = sequenceProgress{stones: stonesScaled, maxStoneFloat: maxStoneFloat, maxStoneInt: maxStone, lastStone: n.Cmp(oneBig) == 0, stonesRaw: stonesF64, stonesString: stonesString, upMoves: up, downMoves: down, upwards: upDirection, steps: steps, number: number}
-				reportChannel <- report
-			}
+		// If the number of steps is a multiple of the report frequency send a report of the trajectory so far
+		if reportChannel != nil && steps%reportFrequency == 0 && n.Cmp(oneBig) != 0 {
+			reportChannel <- sequenceProgress{trajectory: stones.snapshot(), maxStoneInt: new(big.Int).Set(maxStone), upMoves: up, downMoves: down, steps: steps, stoppingTime: stoppingTime, parityHash: parityHash, number: number}
 		}
 	}
 
 	// Create a new record and return it
-	//report = sequenceProgress{maxStoneFloat: maxStoneFloat, maxStoneInt: maxStone, upMoves: up, downMoves: down, steps: steps, maxStoneString: maxStone.String(), number: number, }
-	report = sequenceProgress{stones: stonesScaled, maxStoneFloat: maxStoneFloat, maxStoneInt: maxStone, lastStone: n.Cmp(oneBig) == 0, stonesRaw: stonesF64, stonesString: stonesString, upMoves: up, downMoves: down, upwards: upDirection, steps: steps, number: number}
+	report = sequenceProgress{trajectory: stones, maxStoneInt: maxStone, lastStone: true, upMoves: up, downMoves: down, steps: steps, stoppingTime: stoppingTime, parityHash: parityHash, maxStoneString: maxStone.String(), number: number}
+	if reportChannel != nil {
+		reportChannel <- report
+	}
 
 	return
 }
 
-func CollatzPerf(n big.Int) (record sequenceProgress) {
+func CollatzPerf(n big.Int, m collatzMap) (record sequenceProgress) {
 
 	steps := 0
+	up := 0
+	down := 0
+	stoppingTime := 0
+	parityHash := uint64(fnvOffset64)
 	maxStone := new(big.Int).Set(&n)
 	number := new(big.Int).Set(&n)
 
@@ -100,16 +106,34 @@ func CollatzPerf(n big.Int) (record sequenceProgress) {
 
 		if new(big.Int).Mod(&n, twoBig).Cmp(zeroBig) == 0 {
 			n.Div(&n, twoBig)
+			down++
+			parityHash = hashParity(parityHash, 0)
 		} else {
-			n.Mul(&n, threeBig)
-			n.Add(&n, oneBig)
+			m.oddStep(&n)
+			up++
+			parityHash = hashParity(parityHash, 1)
 		}
 		steps++
 
+		if stoppingTime == 0 && n.Cmp(number) == -1 {
+			stoppingTime = steps
+		}
+
 		if n.Cmp(maxStone) == 1 {
 			maxStone = new(big.Int).Set(&n)
 		}
 	}
-	record = sequenceProgress{maxStoneInt: maxStone, steps: steps, maxStoneString: maxStone.String(), number: number}
+	record = sequenceProgress{maxStoneInt: maxStone, upMoves: up, downMoves: down, steps: steps, stoppingTime: stoppingTime, parityHash: parityHash, maxStoneString: maxStone.String(), number: number}
 	return
 }
+
+// FNV-1a parameters for hashing the parity vector of a sequence
+const fnvOffset64 = 14695981039346656037
+const fnvPrime64 = 1099511628211
+
+// hashParity folds the parity of one step (0 for even, 1 for odd) into an FNV-1a hash
+func hashParity(h uint64, parity byte) uint64 {
+	h ^= uint64(parity)
+	h *= fnvPrime64
+	return h
+}
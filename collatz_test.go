@@ -0,0 +1,156 @@
+package main
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// A006577, the number of steps for n to reach 1, from n = 1
+var oeisSteps = []int{
+	0, 1, 7, 2, 5, 8, 16, 3, 19, 6, 14, 9, 9, 17, 17, 4, 12, 20, 20, 7,
+	7, 15, 15, 10, 23, 10, 111, 18, 18, 18, 106, 5, 26, 13, 13, 21, 21, 21, 34, 8,
+	109, 8, 29, 16, 16, 16, 104, 11, 24, 24, 24, 11, 11, 112, 112, 19, 32, 19, 32, 19,
+	19, 107, 107, 6, 27, 27, 27, 14, 14, 14, 102, 22,
+}
+
+// A025586, the largest value in the trajectory of n, from n = 1
+var oeisMaxStones = []int64{
+	1, 2, 16, 4, 16, 16, 52, 8, 52, 16, 52, 16, 40, 52, 160, 16, 52, 52, 88, 20,
+	64, 52, 160, 24, 88, 40, 9232, 52, 88, 160, 9232, 32, 100, 52, 160, 52, 112, 88, 304, 40,
+	9232, 64, 196, 52, 136, 160, 9232, 48, 148, 88,
+}
+
+// copyOf returns a copy of n to hand to the engine, which works on the digits of the value it is given
+func copyOf(n *big.Int) big.Int {
+	return *new(big.Int).Set(n)
+}
+
+// randomValues returns count values of up to maxBits bits, all at least 2
+func randomValues(count int, maxBits int) []*big.Int {
+	r := rand.New(rand.NewSource(1))
+	values := make([]*big.Int, count)
+	for i := range values {
+		bits := 2 + r.Intn(maxBits-1)
+		n := new(big.Int).Rand(r, new(big.Int).Lsh(oneBig, uint(bits)))
+		values[i] = n.Add(n, twoBig)
+	}
+	return values
+}
+
+func TestCollatzMatchesCollatzPerf(t *testing.T) {
+	for _, m := range []collatzMap{standardMap, shortcutMap} {
+		for _, n := range randomValues(200, 128) {
+			full := Collatz(copyOf(n), m, nil, 100)
+			perf := CollatzPerf(copyOf(n), m)
+
+			if full.steps != perf.steps {
+				t.Errorf("%s map, %s: Collatz took %d steps, CollatzPerf %d", m, n, full.steps, perf.steps)
+			}
+			if full.stoppingTime != perf.stoppingTime {
+				t.Errorf("%s map, %s: Collatz stopping time %d, CollatzPerf %d", m, n, full.stoppingTime, perf.stoppingTime)
+			}
+			if full.parityHash != perf.parityHash {
+				t.Errorf("%s map, %s: the parity hashes differ", m, n)
+			}
+			if full.upMoves != perf.upMoves || full.downMoves != perf.downMoves {
+				t.Errorf("%s map, %s: Collatz moves %d up and %d down, CollatzPerf %d and %d", m, n, full.upMoves, full.downMoves, perf.upMoves, perf.downMoves)
+			}
+			if full.maxStoneInt.Cmp(perf.maxStoneInt) != 0 {
+				t.Errorf("%s map, %s: Collatz max stone %s, CollatzPerf %s", m, n, full.maxStoneInt, perf.maxStoneInt)
+			}
+			if full.number.Cmp(n) != 0 || perf.number.Cmp(n) != 0 {
+				t.Errorf("%s map, %s: the reported number changed", m, n)
+			}
+		}
+	}
+}
+
+func TestCollatzInvariants(t *testing.T) {
+	for _, m := range []collatzMap{standardMap, shortcutMap} {
+		for _, n := range randomValues(200, 128) {
+			report := Collatz(copyOf(n), m, nil, 100)
+
+			if report.steps != report.upMoves+report.downMoves {
+				t.Errorf("%s map, %s: %d steps but %d up and %d down", m, n, report.steps, report.upMoves, report.downMoves)
+			}
+			stones := report.trajectory.Stones(0, report.trajectory.Len())
+			if len(stones) != report.steps+1 {
+				t.Errorf("%s map, %s: %d stones for %d steps", m, n, len(stones), report.steps)
+			}
+			if stones[0].Cmp(n) != 0 {
+				t.Errorf("%s map, %s: the trajectory starts at %s", m, n, stones[0])
+			}
+			if last := stones[len(stones)-1]; last.Cmp(oneBig) != 0 {
+				t.Errorf("%s map, %s: the trajectory ends at %s", m, n, last)
+			}
+			if !report.lastStone {
+				t.Errorf("%s map, %s: the last stone is not flagged", m, n)
+			}
+
+			seen := false
+			for _, stone := range stones {
+				if stone.Cmp(report.maxStoneInt) == 1 {
+					t.Errorf("%s map, %s: stone %s is above the max stone %s", m, n, stone, report.maxStoneInt)
+				}
+				seen = seen || stone.Cmp(report.maxStoneInt) == 0
+			}
+			if !seen {
+				t.Errorf("%s map, %s: the max stone %s is not in the trajectory", m, n, report.maxStoneInt)
+			}
+		}
+	}
+}
+
+func TestCollatzStepsOEIS(t *testing.T) {
+	for idx, want := range oeisSteps {
+		n := big.NewInt(int64(idx + 1))
+		if got := CollatzPerf(copyOf(n), standardMap).steps; got != want {
+			t.Errorf("CollatzPerf(%s) took %d steps, A006577 has %d", n, got, want)
+		}
+		if got := Collatz(copyOf(n), standardMap, nil, 100).steps; got != want {
+			t.Errorf("Collatz(%s) took %d steps, A006577 has %d", n, got, want)
+		}
+	}
+}
+
+func TestCollatzMaxStoneOEIS(t *testing.T) {
+	for idx, want := range oeisMaxStones {
+		n := big.NewInt(int64(idx + 1))
+		if got := CollatzPerf(copyOf(n), standardMap).maxStoneInt; got.Int64() != want {
+			t.Errorf("CollatzPerf(%s) has max stone %s, A025586 has %d", n, got, want)
+		}
+		if got := Collatz(copyOf(n), standardMap, nil, 100).maxStoneInt; got.Int64() != want {
+			t.Errorf("Collatz(%s) has max stone %s, A025586 has %d", n, got, want)
+		}
+	}
+}
+
+// benchmarkValues are a small value, a medium value with a long trajectory and a thousand bit value
+var benchmarkValues = map[string]*big.Int{
+	"Small":       big.NewInt(27),
+	"Medium":      big.NewInt(989345275647),
+	"ThousandBit": new(big.Int).Sub(new(big.Int).Lsh(oneBig, 1000), oneBig),
+}
+
+func BenchmarkCollatzPerf(b *testing.B) {
+	for _, name := range []string{"Small", "Medium", "ThousandBit"} {
+		n := benchmarkValues[name]
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				CollatzPerf(copyOf(n), standardMap)
+			}
+		})
+	}
+}
+
+func BenchmarkCollatz(b *testing.B) {
+	for _, name := range []string{"Small", "Medium", "ThousandBit"} {
+		n := benchmarkValues[name]
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Collatz(copyOf(n), standardMap, nil, 100)
+			}
+		})
+	}
+}
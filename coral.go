@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// The most starting values drawn at once, and the most points of their paths,
+// the margin round the figure and the largest side of the image, beyond which
+// the figure is scaled down to fit
+const maxCoralValues = 10000
+const maxCoralPoints = 2000000
+const coralMargin = 20
+const maxCoralSide = 4096
+
+var coralColourNames = []string{"Start Value", "Steps", "Monochrome"}
+
+// coralPoint is a point of a coral path, in segment lengths
+type coralPoint struct {
+	x, y float64
+}
+
+// coralPath is the trajectory of one starting value drawn backwards from 1
+type coralPath struct {
+	number *big.Int
+	steps  int
+	points []coralPoint
+}
+
+// coralOptions are how the paths are turned and drawn
+type coralOptions struct {
+	evenAngle float64 // degrees turned anticlockwise on an even step
+	oddAngle  float64 // degrees turned anticlockwise on an odd step
+	segment   float64 // length of a step in pixels
+	colours   string  // one of coralColourNames
+}
+
+// The paths last drawn on the Coral tab, which are exported, and whether a drawing is under way
+var coralState struct {
+	paths   []coralPath
+	options coralOptions
+	lower   *big.Int
+	upper   *big.Int
+}
+var coralDrawing atomic.Bool
+var coralCanvas *canvas.Image
+var coralCaption *widget.Label
+
+// coralPaths runs each value from lower to upper to 1 and turns its trajectory
+// into a path that starts at 1, pointing up, and follows the steps backwards,
+// turning by one angle before each even step and the other before each odd one.
+// It stops before the path that would take the points past maxPoints.
+func coralPaths(lower *big.Int, upper *big.Int, m collatzMap, evenAngle float64, oddAngle float64, maxPoints int) []coralPath {
+	even := evenAngle * math.Pi / 180
+	odd := oddAngle * math.Pi / 180
+
+	var paths []coralPath
+	total := 0
+	for n := new(big.Int).Set(lower); n.Cmp(upper) <= 0; n.Add(n, oneBig) {
+		rep := Collatz(*new(big.Int).Set(n), m, nil, 0)
+		t := rep.trajectory
+		if total += t.Len(); total > maxPoints {
+			break
+		}
+
+		heading := math.Pi / 2
+		p := coralPoint{}
+		points := make([]coralPoint, 0, t.Len())
+		points = append(points, p)
+		for i := t.Len() - 1; i > 0; i-- {
+			if t.Upwards(i) {
+				heading += odd
+			} else {
+				heading += even
+			}
+			p = coralPoint{p.x + math.Cos(heading), p.y + math.Sin(heading)}
+			points = append(points, p)
+		}
+		paths = append(paths, coralPath{number: new(big.Int).Set(n), steps: rep.steps, points: points})
+	}
+	return paths
+}
+
+// coralSize returns the size of the image the paths are drawn on, the scale
+// from segment lengths to pixels and the point drawn at the top left of the figure
+func coralSize(paths []coralPath, segment float64) (width int, height int, scale float64, origin coralPoint) {
+	minX, maxX, minY, maxY := 0.0, 0.0, 0.0, 0.0
+	for _, path := range paths {
+		for _, p := range path.points {
+			minX, maxX = math.Min(minX, p.x), math.Max(maxX, p.x)
+			minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+		}
+	}
+
+	scale = segment
+	side := maxCoralSide - 2*coralMargin
+	if extent := math.Max(maxX-minX, maxY-minY) * scale; extent > float64(side) {
+		scale *= float64(side) / extent
+	}
+	width = int(math.Ceil((maxX-minX)*scale)) + 2*coralMargin
+	height = int(math.Ceil((maxY-minY)*scale)) + 2*coralMargin
+	return width, height, scale, coralPoint{minX, maxY}
+}
+
+// renderCoral draws the paths with a renderer from provider, chart.PNG or chart.SVG
+func renderCoral(w io.Writer, paths []coralPath, o coralOptions, provider chart.RendererProvider) error {
+	width, height, scale, origin := coralSize(paths, o.segment)
+	r, err := provider(width, height)
+	if err != nil {
+		return err
+	}
+
+	r.SetFillColor(drawing.ColorWhite)
+	r.MoveTo(0, 0)
+	r.LineTo(width, 0)
+	r.LineTo(width, height)
+	r.LineTo(0, height)
+	r.Close()
+	r.Fill()
+
+	maxSteps := 0
+	for _, path := range paths {
+		maxSteps = max(maxSteps, path.steps)
+	}
+
+	// The paths overlap near 1, so each is drawn part transparent and the shared trunk builds up
+	for i, path := range paths {
+		var c drawing.Color
+		switch o.colours {
+		case "Steps":
+			c = chart.Viridis(float64(path.steps), 0, float64(max(maxSteps, 1)))
+		case "Monochrome":
+			c = drawing.ColorBlack
+		default:
+			c = chart.Viridis(float64(i), 0, float64(max(len(paths)-1, 1)))
+		}
+		r.SetStrokeColor(c.WithAlpha(0x80))
+		r.SetStrokeWidth(1)
+		for j, p := range path.points {
+			x := coralMargin + int(math.Round((p.x-origin.x)*scale))
+			y := coralMargin + int(math.Round((origin.y-p.y)*scale))
+			if j == 0 {
+				r.MoveTo(x, y)
+			} else {
+				r.LineTo(x, y)
+			}
+		}
+		r.Stroke()
+	}
+
+	return r.Save(w)
+}
+
+// drawCoral works out and draws the paths of a range, which the Coral tab shows and exports
+func drawCoral(lower string, upper string, o coralOptions, win fyne.Window) {
+	defer coralDrawing.Store(false)
+
+	base := currentSettings().EntryBase
+	nl, ok := checkValidation(lower, base, win)
+	if !ok {
+		return
+	}
+	nu, ok := checkValidation(upper, base, win)
+	if !ok {
+		return
+	}
+	if nl.Sign() <= 0 || nu.Cmp(&nl) < 0 {
+		showInformation("Range Error", "The lower limit must be at least 1 and no more than the upper limit", win)
+		return
+	}
+	if count := new(big.Int).Sub(&nu, &nl); count.Cmp(big.NewInt(maxCoralValues)) >= 0 {
+		showInformation("Range Error", fmt.Sprintf("At most %d values can be drawn at once", maxCoralValues), win)
+		return
+	}
+
+	updateUI(func() {
+		coralCaption.SetText("Drawing...")
+	})
+	paths := coralPaths(&nl, &nu, currentSettings().Map, o.evenAngle, o.oddAngle, maxCoralPoints)
+	if len(paths) == 0 {
+		showInformation("Range Error", fmt.Sprintf("The path of %s has more than the %d points that can be drawn", formatValue(&nl), maxCoralPoints), win)
+		updateUI(func() {
+			coralCaption.SetText("")
+		})
+		return
+	}
+	last := paths[len(paths)-1].number
+
+	var buffer bytes.Buffer
+	if err := renderCoral(&buffer, paths, o, chart.PNG); err != nil {
+		showInformation("Drawing Error", fmt.Sprintf("The figure could not be drawn: %v", err), win)
+		return
+	}
+
+	coralState.paths = paths
+	coralState.options = o
+	coralState.lower, coralState.upper = &nl, last
+
+	width, height, _, _ := coralSize(paths, o.segment)
+	caption := fmt.Sprintf("%d values from %s to %s, %d × %d pixels", len(paths), formatValue(&nl), formatValue(last), width, height)
+	if last.Cmp(&nu) != 0 {
+		caption += fmt.Sprintf(", stopping before %d points", maxCoralPoints)
+	}
+	res := fyne.NewStaticResource("coral.png", buffer.Bytes())
+	updateUI(func() {
+		coralCaption.SetText(caption)
+		coralCanvas.Resource = res
+		coralCanvas.Show()
+		coralCanvas.Refresh()
+	})
+}
+
+// exportCoral saves the figure last drawn to the export folder as a PNG or SVG
+func exportCoral(format string, win fyne.Window) {
+	defer coralDrawing.Store(false)
+
+	if coralState.paths == nil {
+		showInformation("Export", "Draw a figure first", win)
+		return
+	}
+
+	provider := chart.PNG
+	if format == "svg" {
+		provider = chart.SVG
+	}
+	name := filepath.Join(currentSettings().ExportDir, fmt.Sprintf("coral_%s.%s", abbreviateName(coralState.lower, coralState.upper), format))
+	f, err := os.Create(name)
+	if err == nil {
+		err = renderCoral(f, coralState.paths, coralState.options, provider)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		showInformation("Export Error", fmt.Sprintf("The figure could not be saved: %v", err), win)
+		return
+	}
+	showInformation("Export", fmt.Sprintf("The figure was saved to %s", name), win)
+}
+
+// makeCoralTab draws the trajectories of a range backwards from 1 as paths that
+// turn one way on even steps and another on odd ones, the Collatz "coral"
+func makeCoralTab(win fyne.Window) fyne.CanvasObject {
+
+	entryLower := widget.NewEntry()
+	entryLower.SetText("1")
+	entryUpper := widget.NewEntry()
+	entryUpper.SetText("1000")
+	entryEven := widget.NewEntry()
+	entryEven.SetText("8.65")
+	entryOdd := widget.NewEntry()
+	entryOdd.SetText("-16")
+	entrySegment := widget.NewEntry()
+	entrySegment.SetText("10")
+	for _, e := range []*widget.Entry{entryEven, entryOdd, entrySegment} {
+		e.Validator = func(s string) error {
+			_, err := strconv.ParseFloat(s, 64)
+			return err
+		}
+	}
+	colourSelect := widget.NewSelect(coralColourNames, nil)
+	colourSelect.SetSelected(coralColourNames[0])
+
+	coralCanvas = &canvas.Image{FillMode: canvas.ImageFillContain}
+	coralCanvas.SetMinSize(fyne.NewSize(400, 400))
+	coralCanvas.Hide()
+	coralCaption = widget.NewLabel("")
+
+	// The options are read here, on the UI goroutine, and the figure drawn from them
+	drawBtn := widget.NewButton("Draw", func() {
+		var o coralOptions
+		var errs [3]error
+		o.evenAngle, errs[0] = strconv.ParseFloat(entryEven.Text, 64)
+		o.oddAngle, errs[1] = strconv.ParseFloat(entryOdd.Text, 64)
+		o.segment, errs[2] = strconv.ParseFloat(entrySegment.Text, 64)
+		o.colours = colourSelect.Selected
+		for _, err := range errs {
+			if err != nil {
+				showInformation("Number Format Error", "The angles and segment length must be numbers", win)
+				return
+			}
+		}
+		if o.segment <= 0 {
+			showInformation("Number Format Error", "The segment length must be more than 0", win)
+			return
+		}
+		if !coralDrawing.CompareAndSwap(false, true) {
+			return
+		}
+		go drawCoral(removeSpaces(entryLower.Text), removeSpaces(entryUpper.Text), o, win)
+	})
+	exportBtn := func(format string) *widget.Button {
+		return widget.NewButton("Export "+strings.ToUpper(format), func() {
+			if !coralDrawing.CompareAndSwap(false, true) {
+				return
+			}
+			go exportCoral(format, win)
+		})
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem(fmt.Sprintf("%15s", "Lower Limit:"), entryLower),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Upper Limit:"), entryUpper),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Even Angle:"), entryEven),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Odd Angle:"), entryOdd),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Segment Length:"), entrySegment),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Colour By:"), colourSelect),
+	)
+	buttons := container.NewGridWithColumns(3, drawBtn, exportBtn("png"), exportBtn("svg"))
+	left := container.NewVBox(form, buttons, coralCaption)
+
+	splitCanvas := container.NewHSplit(left, coralCanvas)
+	splitCanvas.Offset = 0.3
+	rememberSplit("split.coral", splitCanvas)
+	return splitCanvas
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+func TestCoralPaths(t *testing.T) {
+	paths := coralPaths(big.NewInt(1), big.NewInt(5), standardMap, 90, -90, maxCoralPoints)
+	if len(paths) != 5 {
+		t.Fatalf("%d paths, expected 5", len(paths))
+	}
+
+	// 1 has no steps, so its path is the point it starts at
+	if p := paths[0]; p.steps != 0 || len(p.points) != 1 || p.points[0] != (coralPoint{}) {
+		t.Errorf("the path of 1 is %+v, expected one point at the origin", p)
+	}
+
+	// 5 -> 16 -> 8 -> 4 -> 2 -> 1 backwards is four even steps then an odd
+	// one, each turning a quarter before a unit step: left, down, right, up, right
+	want := []coralPoint{{0, 0}, {-1, 0}, {-1, -1}, {0, -1}, {0, 0}, {1, 0}}
+	p := paths[4]
+	if p.number.Cmp(big.NewInt(5)) != 0 || p.steps != 5 || len(p.points) != len(want) {
+		t.Fatalf("the path of 5 is %+v, expected 5 steps", p)
+	}
+	for i, w := range want {
+		if math.Abs(p.points[i].x-w.x) > 1e-9 || math.Abs(p.points[i].y-w.y) > 1e-9 {
+			t.Errorf("point %d of the path of 5 is %v, expected %v", i, p.points[i], w)
+		}
+	}
+
+	// 1 to 5 have 1, 2, 8, 3 and 6 points, so a cap of 13 stops before 4
+	if paths := coralPaths(big.NewInt(1), big.NewInt(5), standardMap, 90, -90, 13); len(paths) != 3 {
+		t.Errorf("%d paths fit in 13 points, expected 3", len(paths))
+	}
+}
+
+func TestCoralExport(t *testing.T) {
+	a := test.NewApp()
+	defer a.Quit()
+
+	s := defaultSettings()
+	s.ExportDir = t.TempDir()
+	setSettings(s)
+	defer setSettings(defaultSettings())
+
+	o := coralOptions{evenAngle: 8.65, oddAngle: -16, segment: 10, colours: coralColourNames[0]}
+	coralState.paths = coralPaths(big.NewInt(1), big.NewInt(200), standardMap, o.evenAngle, o.oddAngle, maxCoralPoints)
+	coralState.options = o
+	coralState.lower, coralState.upper = big.NewInt(1), big.NewInt(200)
+	defer func() {
+		coralState.paths = nil
+	}()
+
+	w := a.NewWindow("")
+	for _, format := range []string{"png", "svg"} {
+		coralDrawing.Store(true)
+		exportCoral(format, w)
+		waitForUI()
+		if coralDrawing.Load() {
+			t.Errorf("an export to %s leaves the tab busy", format)
+		}
+	}
+
+	width, height, _, _ := coralSize(coralState.paths, o.segment)
+	f, err := os.Open(filepath.Join(s.ExportDir, "coral_1_200.png"))
+	if err != nil {
+		t.Fatalf("the PNG was not saved: %v", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil || img.Bounds().Dx() != width || img.Bounds().Dy() != height {
+		t.Errorf("the PNG is %v (%v), expected %d × %d", img, err, width, height)
+	}
+
+	svg, err := os.ReadFile(filepath.Join(s.ExportDir, "coral_1_200.svg"))
+	if err != nil {
+		t.Fatalf("the SVG was not saved: %v", err)
+	}
+	// The background then a path for each value
+	if n := strings.Count(string(svg), "<path"); n != 201 {
+		t.Errorf("the SVG has %d paths, expected 201", n)
+	}
+}
+
+func TestCoralImage(t *testing.T) {
+	o := coralOptions{evenAngle: 8.65, oddAngle: -16, segment: 6, colours: coralColourNames[0]}
+	paths := coralPaths(big.NewInt(1), big.NewInt(1000), standardMap, o.evenAngle, o.oddAngle, maxCoralPoints)
+
+	var buffer bytes.Buffer
+	if err := renderCoral(&buffer, paths, o, chart.PNG); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertImageMatches(t, "charts/coral_1000.png", img)
+}
+
+func TestCoralScalesLargeFigures(t *testing.T) {
+	paths := coralPaths(big.NewInt(27), big.NewInt(27), standardMap, 0, 0, maxCoralPoints)
+
+	// 111 steps straight up at 1000 pixels each is scaled down to the largest side
+	width, height, scale, _ := coralSize(paths, 1000)
+	if height != maxCoralSide || width > 2*coralMargin+1 || math.Abs(scale*111-float64(maxCoralSide-2*coralMargin)) > 1e-6 {
+		t.Errorf("a %d × %d image at scale %v, expected %d high", width, height, scale, maxCoralSide)
+	}
+}
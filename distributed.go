@@ -0,0 +1,500 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/rpc"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for a distributed sweep
+var defaultBlockSize int64 = 10000
+var defaultLeaseTimeout = 2 * time.Minute
+
+// How long a worker waits before asking again when every block is leased out
+var leaseRetryInterval = time.Second
+
+// LeaseRequest asks the coordinator for a block of values
+type LeaseRequest struct {
+	Worker string
+}
+
+// LeaseReply is a block of values [Lower, Upper) leased to a worker. Done is set
+// once the sweep is complete, and Wait when every remaining block is leased out.
+type LeaseReply struct {
+	ID    int64
+	Lower string
+	Upper string
+	Map   int
+	Done  bool
+	Wait  bool
+}
+
+// BlockSummary is what a worker reports back for the values it has computed
+type BlockSummary struct {
+	Count                int64
+	HighwaterSteps       int
+	HighwaterStepsNumber string
+	HighwaterStone       string
+	HighwaterStoneNumber string
+	Histogram            map[int]int64
+}
+
+// BlockReport returns the summary of a leased block to the coordinator
+type BlockReport struct {
+	Worker  string
+	LeaseID int64
+	Lower   string
+	Summary BlockSummary
+}
+
+type ReportReply struct {
+	Accepted bool
+}
+
+// remoteWorker is what the coordinator knows about a connected worker
+type remoteWorker struct {
+	Name     string
+	Blocks   int64
+	Values   int64
+	Leased   int
+	LastSeen time.Time
+}
+
+type blockLease struct {
+	id      int64
+	lower   *big.Int
+	upper   *big.Int
+	worker  string
+	expires time.Time
+}
+
+// coordinator splits [lower, upper) into blocks and leases them to workers.
+// A lease that is not reported before it expires is handed to the next worker.
+type coordinator struct {
+	sync.Mutex
+	mapping      collatzMap
+	lower        *big.Int
+	upper        *big.Int
+	blockSize    int64
+	leaseTimeout time.Duration
+	next         *big.Int
+	nextLeaseID  int64
+	leases       map[int64]*blockLease
+	reassign     []*big.Int
+	completed    map[string]bool
+	totalBlocks  int64
+	doneBlocks   int64
+	summary      BlockSummary
+	maxStone     *big.Int
+	workers      map[string]*remoteWorker
+	done         chan bool
+	listener     net.Listener
+	conns        map[net.Conn]bool
+	closed       bool
+	paused       bool
+	stepBlocks   int
+	stop         chan bool
+	stopOnce     sync.Once
+}
+
+// CoordinatorService is the RPC face of the coordinator
+type CoordinatorService struct {
+	c *coordinator
+}
+
+func newCoordinator(lower *big.Int, upper *big.Int, mapping collatzMap, blockSize int64, leaseTimeout time.Duration) (*coordinator, error) {
+	if blockSize < 1 {
+		return nil, errors.New("the block size must be at least 1")
+	}
+	span := new(big.Int).Sub(upper, lower)
+	if span.Sign() < 0 {
+		return nil, errors.New("Upper limit is smaller than the lower limit")
+	}
+	blocks := new(big.Int).Add(span, big.NewInt(blockSize-1))
+	blocks.Quo(blocks, big.NewInt(blockSize))
+	if !blocks.IsInt64() {
+		return nil, errors.New("the range has too many blocks")
+	}
+
+	c := &coordinator{
+		mapping:      mapping,
+		lower:        new(big.Int).Set(lower),
+		upper:        new(big.Int).Set(upper),
+		blockSize:    blockSize,
+		leaseTimeout: leaseTimeout,
+		next:         new(big.Int).Set(lower),
+		leases:       make(map[int64]*blockLease),
+		completed:    make(map[string]bool),
+		totalBlocks:  blocks.Int64(),
+		summary:      BlockSummary{Histogram: make(map[int]int64)},
+		maxStone:     big.NewInt(0),
+		workers:      make(map[string]*remoteWorker),
+		conns:        make(map[net.Conn]bool),
+		done:         make(chan bool),
+		stop:         make(chan bool),
+	}
+	if c.totalBlocks == 0 {
+		close(c.done)
+	}
+	return c, nil
+}
+
+// Listen starts serving the coordinator protocol on addr
+func (c *coordinator) Listen(addr string) (net.Addr, error) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Coordinator", &CoordinatorService{c: c}); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c.Lock()
+	c.listener = listener
+	c.Unlock()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// A connection that races Close is dropped with the others
+			c.Lock()
+			if c.closed {
+				c.Unlock()
+				conn.Close()
+				return
+			}
+			c.conns[conn] = true
+			c.Unlock()
+			go func() {
+				server.ServeConn(conn)
+				c.Lock()
+				delete(c.conns, conn)
+				c.Unlock()
+			}()
+		}
+	}()
+	return listener.Addr(), nil
+}
+
+// Close stops listening and drops the workers that are connected
+func (c *coordinator) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.listener == nil || c.closed {
+		return nil
+	}
+	c.closed = true
+	for conn := range c.conns {
+		conn.Close()
+	}
+	return c.listener.Close()
+}
+
+// Done is closed once every block has been reported
+func (c *coordinator) Done() chan bool {
+	return c.done
+}
+
+// Pause stops new leases being handed out. Leases already out still complete.
+func (c *coordinator) Pause() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.paused = true
+}
+
+// Step hands out a single further lease and stays paused
+func (c *coordinator) Step() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.paused = true
+	c.stepBlocks++
+}
+
+func (c *coordinator) Resume() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.paused = false
+	c.stepBlocks = 0
+}
+
+// Stop ends the sweep early. Stopped is closed so whoever runs the coordinator can shut it down.
+func (c *coordinator) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+func (c *coordinator) Stopped() chan bool {
+	return c.stop
+}
+
+// Progress returns the fraction of blocks completed, the merged summary and the workers seen so far
+func (c *coordinator) Progress() (float64, BlockSummary, []remoteWorker) {
+	c.Lock()
+	defer c.Unlock()
+
+	fraction := 1.0
+	if c.totalBlocks > 0 {
+		fraction = float64(c.doneBlocks) / float64(c.totalBlocks)
+	}
+
+	summary := c.summary
+	summary.Histogram = make(map[int]int64, len(c.summary.Histogram))
+	for steps, count := range c.summary.Histogram {
+		summary.Histogram[steps] = count
+	}
+
+	workers := make([]remoteWorker, 0, len(c.workers))
+	for _, w := range c.workers {
+		workers = append(workers, *w)
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Name < workers[j].Name })
+
+	return fraction, summary, workers
+}
+
+func (c *coordinator) worker(name string) *remoteWorker {
+	w, ok := c.workers[name]
+	if !ok {
+		w = &remoteWorker{Name: name}
+		c.workers[name] = w
+	}
+	w.LastSeen = time.Now()
+	return w
+}
+
+// Lease hands the worker the next block, preferring blocks whose lease has
+// expired. Once the sweep is stopped every worker is told it is done.
+func (s *CoordinatorService) Lease(req LeaseRequest, reply *LeaseReply) error {
+	c := s.c
+	c.Lock()
+	defer c.Unlock()
+
+	w := c.worker(req.Worker)
+	now := time.Now()
+
+	for id, l := range c.leases {
+		if now.After(l.expires) {
+			delete(c.leases, id)
+			c.workers[l.worker].Leased--
+			if !c.completed[l.lower.String()] {
+				c.reassign = append(c.reassign, l.lower)
+			}
+		}
+	}
+
+	if c.doneBlocks == c.totalBlocks {
+		reply.Done = true
+		return nil
+	}
+	select {
+	case <-c.stop:
+		reply.Done = true
+		return nil
+	default:
+	}
+	if c.paused {
+		if c.stepBlocks == 0 {
+			reply.Wait = true
+			return nil
+		}
+		c.stepBlocks--
+	}
+
+	var lower *big.Int
+	for len(c.reassign) > 0 && lower == nil {
+		candidate := c.reassign[0]
+		c.reassign = c.reassign[1:]
+		if !c.completed[candidate.String()] {
+			lower = candidate
+		}
+	}
+	if lower == nil && c.next.Cmp(c.upper) == -1 {
+		lower = new(big.Int).Set(c.next)
+		c.next.Add(c.next, big.NewInt(c.blockSize))
+	}
+	if lower == nil {
+		reply.Wait = true
+		return nil
+	}
+
+	upper := new(big.Int).Add(lower, big.NewInt(c.blockSize))
+	if upper.Cmp(c.upper) == 1 {
+		upper.Set(c.upper)
+	}
+
+	c.nextLeaseID++
+	c.leases[c.nextLeaseID] = &blockLease{id: c.nextLeaseID, lower: lower, upper: upper, worker: req.Worker, expires: now.Add(c.leaseTimeout)}
+	w.Leased++
+
+	reply.ID = c.nextLeaseID
+	reply.Lower = lower.String()
+	reply.Upper = upper.String()
+	reply.Map = int(c.mapping)
+	return nil
+}
+
+// Report merges the summary of a block. Only a report of an outstanding lease
+// is merged, so a late report from a worker whose lease expired is dropped and a
+// block is never counted twice. A report that does not match what was leased,
+// or whose summary cannot be merged, leaves the block to be leased again.
+func (s *CoordinatorService) Report(report BlockReport, reply *ReportReply) error {
+	c := s.c
+	c.Lock()
+	defer c.Unlock()
+
+	l, ok := c.leases[report.LeaseID]
+	if !ok {
+		if report.LeaseID < 1 || report.LeaseID > c.nextLeaseID {
+			return fmt.Errorf("no block was leased as %d", report.LeaseID)
+		}
+		return nil
+	}
+	if report.Worker != l.worker || report.Lower != l.lower.String() {
+		return fmt.Errorf("lease %d is of the block from %s, leased to %s", report.LeaseID, l.lower, l.worker)
+	}
+
+	w := c.worker(report.Worker)
+	delete(c.leases, report.LeaseID)
+	w.Leased--
+	if c.completed[report.Lower] {
+		return nil
+	}
+	size := new(big.Int).Sub(l.upper, l.lower).Int64()
+	if report.Summary.Count != size {
+		c.reassign = append(c.reassign, l.lower)
+		return fmt.Errorf("the block from %s has %d values, not %d", report.Lower, size, report.Summary.Count)
+	}
+	if err := c.merge(report.Summary); err != nil {
+		c.reassign = append(c.reassign, l.lower)
+		return err
+	}
+
+	c.completed[report.Lower] = true
+	c.doneBlocks++
+	w.Blocks++
+	w.Values += report.Summary.Count
+	reply.Accepted = true
+
+	if c.doneBlocks == c.totalBlocks {
+		close(c.done)
+	}
+	return nil
+}
+
+// merge folds a block summary into the sweep, or leaves it untouched if the summary is not valid
+func (c *coordinator) merge(b BlockSummary) error {
+	var stone *big.Int
+	if b.HighwaterStone != "" {
+		var ok bool
+		if stone, ok = new(big.Int).SetString(b.HighwaterStone, 10); !ok {
+			return fmt.Errorf("invalid high water stone %q", b.HighwaterStone)
+		}
+	}
+
+	s := &c.summary
+	s.Count += b.Count
+	for steps, count := range b.Histogram {
+		s.Histogram[steps] += count
+	}
+	if b.HighwaterSteps > s.HighwaterSteps || s.HighwaterStepsNumber == "" {
+		s.HighwaterSteps = b.HighwaterSteps
+		s.HighwaterStepsNumber = b.HighwaterStepsNumber
+	}
+	if stone != nil && stone.Cmp(c.maxStone) == 1 {
+		c.maxStone = stone
+		s.HighwaterStone = b.HighwaterStone
+		s.HighwaterStoneNumber = b.HighwaterStoneNumber
+	}
+	return nil
+}
+
+// computeBlock runs [lower, upper) through the worker pool and summarises the results
+func computeBlock(lower *big.Int, upper *big.Int, mapping collatzMap) (BlockSummary, error) {
+	tracker, err := newSweepTracker(lower, upper)
+	if err != nil {
+		return BlockSummary{}, err
+	}
+	session := newRunSession(tracker, mapping, nil)
+	session.Run()
+
+	state := session.State()
+	return BlockSummary{
+		Count:                state.Completed,
+		HighwaterSteps:       state.HighwaterSteps,
+		HighwaterStepsNumber: state.HighwaterStepsNumber,
+		HighwaterStone:       state.HighwaterStone,
+		HighwaterStoneNumber: state.HighwaterStoneNumber,
+		Histogram:            state.Histogram,
+	}, nil
+}
+
+// runRemoteWorker leases blocks from the coordinator at addr and computes them
+// on the local worker pool until the sweep is complete or stop is closed
+func runRemoteWorker(addr string, name string, stop chan bool) error {
+	client, err := rpc.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		var lease LeaseReply
+		if err := client.Call("Coordinator.Lease", LeaseRequest{Worker: name}, &lease); err != nil {
+			return err
+		}
+		if lease.Done {
+			return nil
+		}
+		if lease.Wait {
+			select {
+			case <-stop:
+				return nil
+			case <-time.After(leaseRetryInterval):
+			}
+			continue
+		}
+
+		lower, ok := new(big.Int).SetString(lease.Lower, 10)
+		if !ok {
+			return fmt.Errorf("coordinator sent an invalid lower limit %q", lease.Lower)
+		}
+		upper, ok := new(big.Int).SetString(lease.Upper, 10)
+		if !ok {
+			return fmt.Errorf("coordinator sent an invalid upper limit %q", lease.Upper)
+		}
+
+		if lease.Map < 0 || lease.Map >= len(collatzMapNames) {
+			return fmt.Errorf("coordinator sent an unknown map %d", lease.Map)
+		}
+
+		summary, err := computeBlock(lower, upper, collatzMap(lease.Map))
+		if err != nil {
+			return err
+		}
+
+		var reply ReportReply
+		report := BlockReport{Worker: name, LeaseID: lease.ID, Lower: lease.Lower, Summary: summary}
+		if err := client.Call("Coordinator.Report", report, &reply); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/rpc"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startCoordinator serves a coordinator for [lower, upper) on a free local port
+func startCoordinator(t *testing.T, lower int64, upper int64, blockSize int64, leaseTimeout time.Duration) (*coordinator, string) {
+	t.Helper()
+	startTestPool()
+
+	c, err := newCoordinator(big.NewInt(lower), big.NewInt(upper), standardMap, blockSize, leaseTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := c.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+	})
+	return c, addr.String()
+}
+
+// startWorkers runs count remote workers against addr, returning where their errors are sent
+func startWorkers(addr string, count int, stop chan bool) chan error {
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("worker %d", i)
+		go func() {
+			errs <- runRemoteWorker(addr, name, stop)
+		}()
+	}
+	return errs
+}
+
+// localBlock computes [lower, upper) on the local worker pool
+func localBlock(t *testing.T, lower int64, upper int64) BlockSummary {
+	t.Helper()
+	summary, err := computeBlock(big.NewInt(lower), big.NewInt(upper), standardMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return summary
+}
+
+func waitForCoordinator(t *testing.T, c *coordinator) {
+	t.Helper()
+	select {
+	case <-c.Done():
+	case <-time.After(60 * time.Second):
+		t.Fatal("timed out waiting for the sweep")
+	}
+}
+
+// checkSummary compares a merged summary with one computed locally. Values that
+// tie for a high water mark may be reported in either order, so the values
+// named are checked by running them.
+func checkSummary(t *testing.T, got BlockSummary, want BlockSummary) {
+	t.Helper()
+	if got.Count != want.Count || got.HighwaterSteps != want.HighwaterSteps || got.HighwaterStone != want.HighwaterStone {
+		t.Errorf("the merged summary is %d values, %d steps and a stone of %s, expected %d, %d and %s",
+			got.Count, got.HighwaterSteps, got.HighwaterStone, want.Count, want.HighwaterSteps, want.HighwaterStone)
+	}
+	if !reflect.DeepEqual(got.Histogram, want.Histogram) {
+		t.Errorf("the merged histogram is %v, expected %v", got.Histogram, want.Histogram)
+	}
+	steps, _ := new(big.Int).SetString(got.HighwaterStepsNumber, 10)
+	if steps == nil || CollatzPerf(*steps, standardMap).steps != want.HighwaterSteps {
+		t.Errorf("%s does not take the most steps", got.HighwaterStepsNumber)
+	}
+	stone, _ := new(big.Int).SetString(got.HighwaterStoneNumber, 10)
+	if stone == nil || CollatzPerf(*stone, standardMap).maxStoneString != want.HighwaterStone {
+		t.Errorf("%s does not reach the largest stone", got.HighwaterStoneNumber)
+	}
+}
+
+func TestDistributedSweep(t *testing.T) {
+	c, addr := startCoordinator(t, 1, 10001, 500, defaultLeaseTimeout)
+	errs := startWorkers(addr, 3, make(chan bool))
+	waitForCoordinator(t, c)
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("a worker failed: %v", err)
+		}
+	}
+
+	fraction, summary, workers := c.Progress()
+	if fraction != 1 || len(workers) != 3 {
+		t.Errorf("%.2f of the blocks were done by %d workers, expected all of them by 3", fraction, len(workers))
+	}
+	blocks := int64(0)
+	for _, w := range workers {
+		blocks += w.Blocks
+	}
+	if blocks != 20 {
+		t.Errorf("the workers reported %d blocks, expected 20", blocks)
+	}
+	checkSummary(t, summary, localBlock(t, 1, 10001))
+}
+
+func TestLeaseExpiry(t *testing.T) {
+	c, addr := startCoordinator(t, 1, 2001, 1000, 100*time.Millisecond)
+	service := &CoordinatorService{c: c}
+
+	// A worker leases the first block and is never heard from again
+	var lost LeaseReply
+	if err := service.Lease(LeaseRequest{Worker: "lost"}, &lost); err != nil || lost.Lower != "1" {
+		t.Fatalf("the first lease is %+v (%v), expected the block from 1", lost, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	// Its block is handed to the next worker once the lease has expired
+	var again LeaseReply
+	if err := service.Lease(LeaseRequest{Worker: "next"}, &again); err != nil || again.Lower != "1" || again.ID == lost.ID {
+		t.Fatalf("the next lease is %+v (%v), expected the block from 1 again", again, err)
+	}
+	var reply ReportReply
+	if err := service.Report(BlockReport{Worker: "next", LeaseID: again.ID, Lower: again.Lower, Summary: localBlock(t, 1, 1001)}, &reply); err != nil || !reply.Accepted {
+		t.Fatalf("the report was not accepted: %v", err)
+	}
+
+	// A real worker finishes the sweep, and the lost worker's late report is not counted twice
+	errs := startWorkers(addr, 1, make(chan bool))
+	waitForCoordinator(t, c)
+	if err := <-errs; err != nil {
+		t.Errorf("the worker failed: %v", err)
+	}
+	reply = ReportReply{}
+	if err := service.Report(BlockReport{Worker: "lost", LeaseID: lost.ID, Lower: lost.Lower, Summary: localBlock(t, 1, 1001)}, &reply); err != nil || reply.Accepted {
+		t.Errorf("the late report was accepted (%v)", err)
+	}
+	_, summary, _ := c.Progress()
+	checkSummary(t, summary, localBlock(t, 1, 2001))
+}
+
+func TestReportNotMerged(t *testing.T) {
+	c, _ := startCoordinator(t, 1, 11, 10, defaultLeaseTimeout)
+	service := &CoordinatorService{c: c}
+
+	var lease LeaseReply
+	service.Lease(LeaseRequest{Worker: "w"}, &lease)
+	var reply ReportReply
+	bad := BlockSummary{Count: 10, HighwaterStone: "lots", Histogram: map[int]int64{1: 10}}
+	if err := service.Report(BlockReport{Worker: "w", LeaseID: lease.ID, Lower: lease.Lower, Summary: bad}, &reply); err == nil || reply.Accepted {
+		t.Fatalf("a summary that cannot be merged was accepted")
+	}
+	if fraction, summary, _ := c.Progress(); fraction != 0 || summary.Count != 0 || len(summary.Histogram) != 0 {
+		t.Errorf("%.2f of the blocks are done with %d values, expected nothing merged", fraction, summary.Count)
+	}
+
+	// The block is leased again and completes the sweep
+	lease = LeaseReply{}
+	if err := service.Lease(LeaseRequest{Worker: "w"}, &lease); err != nil || lease.Lower != "1" {
+		t.Fatalf("the block was not leased again: %+v (%v)", lease, err)
+	}
+	service.Report(BlockReport{Worker: "w", LeaseID: lease.ID, Lower: lease.Lower, Summary: localBlock(t, 1, 11)}, &reply)
+	waitForCoordinator(t, c)
+}
+
+func TestReportNotLeased(t *testing.T) {
+	c, _ := startCoordinator(t, 1, 16, 10, defaultLeaseTimeout)
+	service := &CoordinatorService{c: c}
+
+	var first, second LeaseReply
+	service.Lease(LeaseRequest{Worker: "w"}, &first)
+	service.Lease(LeaseRequest{Worker: "w"}, &second)
+	for _, report := range []BlockReport{
+		{Worker: "w", LeaseID: 99, Lower: "1", Summary: localBlock(t, 1, 11)},
+		{Worker: "w", LeaseID: first.ID, Lower: "2", Summary: localBlock(t, 2, 12)},
+		{Worker: "other", LeaseID: first.ID, Lower: "1", Summary: localBlock(t, 1, 11)},
+		{Worker: "w", LeaseID: second.ID, Lower: "11", Summary: localBlock(t, 11, 21)},
+	} {
+		var reply ReportReply
+		if err := service.Report(report, &reply); err == nil || reply.Accepted {
+			t.Errorf("the report of lease %d from %s for %d values was accepted", report.LeaseID, report.Lower, report.Summary.Count)
+		}
+	}
+	if fraction, summary, _ := c.Progress(); fraction != 0 || summary.Count != 0 {
+		t.Errorf("%.2f of the blocks are done with %d values, expected nothing merged", fraction, summary.Count)
+	}
+
+	// The block whose report had the wrong count is leased again, and the other is still out
+	var again LeaseReply
+	if err := service.Lease(LeaseRequest{Worker: "w"}, &again); err != nil || again.Lower != "11" {
+		t.Fatalf("the next lease is %+v (%v), expected the block from 11 again", again, err)
+	}
+	var reply ReportReply
+	service.Report(BlockReport{Worker: "w", LeaseID: first.ID, Lower: "1", Summary: localBlock(t, 1, 11)}, &reply)
+	service.Report(BlockReport{Worker: "w", LeaseID: again.ID, Lower: "11", Summary: localBlock(t, 11, 16)}, &reply)
+	waitForCoordinator(t, c)
+}
+
+func TestCoordinatorStop(t *testing.T) {
+	c, addr := startCoordinator(t, 1, 1000001, 100, defaultLeaseTimeout)
+	errs := startWorkers(addr, 2, make(chan bool))
+	for {
+		if fraction, _, _ := c.Progress(); fraction > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Workers are told the sweep is done, or dropped when the coordinator closes
+	c.Stop()
+	var lease LeaseReply
+	if err := (&CoordinatorService{c: c}).Lease(LeaseRequest{Worker: "late"}, &lease); err != nil || !lease.Done {
+		t.Errorf("a lease after the stop is %+v (%v), expected done", lease, err)
+	}
+	c.Close()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-errs:
+		case <-time.After(30 * time.Second):
+			t.Fatal("a worker carried on after the stop")
+		}
+	}
+	if fraction, _, _ := c.Progress(); fraction == 1 {
+		t.Errorf("the whole range was run after the stop")
+	}
+}
+
+// badMapService is a coordinator that leases blocks of a map the worker does not know
+type badMapService struct{}
+
+func (badMapService) Lease(req LeaseRequest, reply *LeaseReply) error {
+	*reply = LeaseReply{ID: 1, Lower: "1", Upper: "10", Map: len(collatzMapNames)}
+	return nil
+}
+
+func TestRemoteWorkerUnknownMap(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Coordinator", badMapService{}); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go server.Accept(listener)
+
+	if err := runRemoteWorker(listener.Addr().String(), "w", make(chan bool)); err == nil || !strings.Contains(err.Error(), "unknown map") {
+		t.Errorf("the worker returned %v, expected an unknown map", err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// progressEvent is pushed to clients watching a range run. Records set since
+// the previous event are included so that none are missed between events.
+type progressEvent struct {
+	Job                  string        `json:"job"`
+	Status               string        `json:"status"`
+	Processed            int64         `json:"processed"`
+	Total                int64         `json:"total"`
+	Percent              float64       `json:"percent"`
+	ValuesPerSecond      float64       `json:"valuesPerSecond"`
+	HighwaterSteps       int           `json:"highwaterSteps"`
+	HighwaterStepsNumber string        `json:"highwaterStepsNumber"`
+	HighwaterStone       string        `json:"highwaterStone"`
+	HighwaterStoneNumber string        `json:"highwaterStoneNumber"`
+	NewStepRecords       []sweepRecord `json:"newStepRecords,omitempty"`
+	NewStoneRecords      []sweepRecord `json:"newStoneRecords,omitempty"`
+}
+
+// eventBroadcaster fans progress events out to any number of subscribers.
+// A subscriber that falls behind misses events rather than slowing the run.
+type eventBroadcaster struct {
+	sync.Mutex
+	subscribers map[chan progressEvent]bool
+	closed      bool
+}
+
+func newEventBroadcaster() *eventBroadcaster {
+	return &eventBroadcaster{subscribers: make(map[chan progressEvent]bool)}
+}
+
+// Subscribe returns a channel of events, which is closed once the run is over
+func (b *eventBroadcaster) Subscribe() chan progressEvent {
+	b.Lock()
+	defer b.Unlock()
+
+	c := make(chan progressEvent, 16)
+	if b.closed {
+		close(c)
+		return c
+	}
+	b.subscribers[c] = true
+	return c
+}
+
+func (b *eventBroadcaster) Unsubscribe(c chan progressEvent) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.subscribers[c] {
+		delete(b.subscribers, c)
+		close(c)
+	}
+}
+
+func (b *eventBroadcaster) Publish(e progressEvent) {
+	b.Lock()
+	defer b.Unlock()
+
+	for c := range b.subscribers {
+		select {
+		case c <- e:
+		default:
+		}
+	}
+}
+
+// Close ends every subscription
+func (b *eventBroadcaster) Close() {
+	b.Lock()
+	defer b.Unlock()
+
+	for c := range b.subscribers {
+		delete(b.subscribers, c)
+		close(c)
+	}
+	b.closed = true
+}
+
+// writeServerSentEvent writes one event in the text/event-stream format
+func writeServerSentEvent(w io.Writer, name string, e progressEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
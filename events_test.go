@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serverSentEvent is one event read from a text/event-stream
+type serverSentEvent struct {
+	name  string
+	event progressEvent
+}
+
+// readEvents reads the events of a job's stream until the server ends it
+func readEvents(t *testing.T, server *httptest.Server, id string) []serverSentEvent {
+	t.Helper()
+	resp, err := http.Get(server.URL + "/jobs/" + id + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("the stream returned %d with %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var events []serverSentEvent
+	var e serverSentEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			e.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.event); err != nil {
+				t.Fatalf("the event %q is not JSON: %v", line, err)
+			}
+		case line == "":
+			events = append(events, e)
+			e = serverSentEvent{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestJobEventStream(t *testing.T) {
+	server := startTestServer(t)
+	id := startRangeJob(t, server, `{"lower": "1", "upper": "100000", "reportFrequency": 1000}`)
+	events := readEvents(t, server, id)
+
+	if len(events) < 2 {
+		t.Fatalf("%d events arrived, expected progress and then the end of the job", len(events))
+	}
+	var processed int64
+	for _, e := range events[:len(events)-1] {
+		if e.name != "progress" || e.event.Job != id || e.event.Status != sessionRunning {
+			t.Errorf("the event %s is %+v, expected the progress of job %s", e.name, e.event, id)
+		}
+		if e.event.Processed < processed || e.event.Total != 100000 {
+			t.Errorf("the progress went from %d to %d of %d", processed, e.event.Processed, e.event.Total)
+		}
+		processed = e.event.Processed
+	}
+
+	last := events[len(events)-1]
+	if last.name != "done" || last.event.Status != sessionFinished || last.event.Processed != 100000 || last.event.Percent != 100 {
+		t.Errorf("the last event %s is %+v, expected the job finished after 100000 values", last.name, last.event)
+	}
+	if last.event.HighwaterSteps != 350 || last.event.HighwaterStepsNumber != "77031" {
+		t.Errorf("the most steps are %d for %s, expected 350 for 77031", last.event.HighwaterSteps, last.event.HighwaterStepsNumber)
+	}
+}
+
+func TestFinishedJobEventStream(t *testing.T) {
+	server := startTestServer(t)
+	id := startRangeJob(t, server, `{"lower": "1", "upper": "100"}`)
+
+	var status jobStatus
+	waitUntil(t, "the job to finish", func() bool {
+		request(t, http.MethodGet, server.URL+"/jobs/"+id, "", &status)
+		return status.Status != sessionRunning
+	})
+
+	// A stream of a job that has ended is only its final state
+	events := readEvents(t, server, id)
+	if len(events) != 1 || events[0].name != "done" || events[0].event.Status != sessionFinished || events[0].event.Processed != 100 {
+		t.Errorf("the events are %+v, expected the one for the finished job", events)
+	}
+}
+
+func TestEventBroadcaster(t *testing.T) {
+	b := newEventBroadcaster()
+	first, second := b.Subscribe(), b.Subscribe()
+	b.Unsubscribe(second)
+	if _, ok := <-second; ok {
+		t.Error("the channel of a subscriber that left is still open")
+	}
+
+	b.Publish(progressEvent{Processed: 1})
+	if e := <-first; e.Processed != 1 {
+		t.Errorf("the event is %+v, expected the one published", e)
+	}
+
+	// A subscriber that falls behind misses events instead of blocking the run
+	for i := 0; i < 100; i++ {
+		b.Publish(progressEvent{Processed: int64(i)})
+	}
+	b.Close()
+	count := 0
+	for range first {
+		count++
+	}
+	if count != cap(first) {
+		t.Errorf("%d events were kept, expected %d", count, cap(first))
+	}
+	if _, ok := <-b.Subscribe(); ok {
+		t.Error("a subscription after the end of the run is open")
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"unicode"
+)
+
+// Limits that stop an expression from exhausting memory
+const maxExpressionBits = 1 << 20
+const maxFactorial = 20000
+
+// exprError is a fault in an expression at a position counted from 1
+type exprError struct {
+	pos int
+	msg string
+}
+
+func (e *exprError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.msg, e.pos)
+}
+
+// exprParser evaluates expressions over big integers. Numbers are read in
+// base, or by their 0x, 0b or 0o prefix when base is 0.
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { "*" unary }
+//	unary   = ("+" | "-") unary | power
+//	power   = postfix [ "^" unary ]
+//	postfix = primary { "!" }
+//	primary = number | "(" expr ")" | "mersenne" "(" expr ")"
+type exprParser struct {
+	input []rune
+	pos   int
+	base  int
+}
+
+// evalExpression evaluates s exactly, for example 2^100+1, 27*3^50 or mersenne(127)
+func evalExpression(s string, base int) (*big.Int, error) {
+	p := &exprParser{input: []rune(s), base: base}
+	v, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+	return v, nil
+}
+
+// evalPositive evaluates s as evalExpression does and refuses a value below 1,
+// which no starting value, limit or count may be. The whole entry is at fault.
+func evalPositive(s string, base int) (*big.Int, error) {
+	v, err := evalExpression(s, base)
+	if err != nil {
+		return nil, err
+	}
+	if v.Sign() < 1 {
+		return nil, &exprError{pos: 1, msg: fmt.Sprintf("the value %s is less than 1", v)}
+	}
+	return v, nil
+}
+
+// evalNonNegative evaluates s as evalExpression does and refuses a value below 0,
+// as a residue may be 0
+func evalNonNegative(s string, base int) (*big.Int, error) {
+	v, err := evalExpression(s, base)
+	if err != nil {
+		return nil, err
+	}
+	if v.Sign() < 0 {
+		return nil, &exprError{pos: 1, msg: fmt.Sprintf("the value %s is less than 0", v)}
+	}
+	return v, nil
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return &exprError{pos: p.pos + 1, msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// accept consumes the next rune if it is one of want
+func (p *exprParser) accept(want ...rune) (rune, bool) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0, false
+	}
+	for _, r := range want {
+		if p.input[p.pos] == r {
+			p.pos++
+			return r, true
+		}
+	}
+	return 0, false
+}
+
+func (p *exprParser) expr() (*big.Int, error) {
+	v, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		// The minus sign may also be typed as U+2212
+		op, ok := p.accept('+', '-', '−')
+		if !ok {
+			return v, nil
+		}
+		rhs, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		if op == '+' {
+			v.Add(v, rhs)
+		} else {
+			v.Sub(v, rhs)
+		}
+		if v.BitLen() > maxExpressionBits {
+			return nil, p.errorf("the value is larger than %d bits", maxExpressionBits)
+		}
+	}
+}
+
+func (p *exprParser) term() (*big.Int, error) {
+	v, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept('*', '×'); !ok {
+			return v, nil
+		}
+		start := p.pos
+		rhs, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if v.BitLen()+rhs.BitLen() > maxExpressionBits {
+			p.pos = start
+			return nil, p.errorf("the product is larger than %d bits", maxExpressionBits)
+		}
+		v.Mul(v, rhs)
+	}
+}
+
+func (p *exprParser) unary() (*big.Int, error) {
+	if op, ok := p.accept('+', '-', '−'); ok {
+		v, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if op != '+' {
+			v.Neg(v)
+		}
+		return v, nil
+	}
+	return p.power()
+}
+
+func (p *exprParser) power() (*big.Int, error) {
+	v, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept('^'); !ok {
+		return v, nil
+	}
+	p.skipSpaces()
+	start := p.pos
+	exp, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if exp.Sign() < 0 {
+		p.pos = start
+		return nil, p.errorf("the exponent %s is negative", exp)
+	}
+	// 0, 1 and -1 stay small whatever the exponent. Any other power has at most
+	// BitLen bits for each time v is multiplied in.
+	if v.CmpAbs(oneBig) > 0 && (!exp.IsInt64() || exp.Int64() > maxExpressionBits || int64(v.BitLen())*exp.Int64() > maxExpressionBits) {
+		p.pos = start
+		return nil, p.errorf("the power is larger than %d bits", maxExpressionBits)
+	}
+	return v.Exp(v, exp, nil), nil
+}
+
+func (p *exprParser) postfix() (*big.Int, error) {
+	v, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpaces()
+		start := p.pos
+		if _, ok := p.accept('!'); !ok {
+			return v, nil
+		}
+		if v.Sign() < 0 || !v.IsInt64() || v.Int64() > maxFactorial {
+			p.pos = start
+			return nil, p.errorf("the factorial of %s is out of range, it must be between 0 and %d", v, maxFactorial)
+		}
+		v = new(big.Int).MulRange(1, v.Int64())
+	}
+}
+
+func (p *exprParser) primary() (*big.Int, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return nil, p.errorf("expected a value")
+	}
+
+	if _, ok := p.accept('('); ok {
+		v, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(')'); !ok {
+			return nil, p.errorf("expected )")
+		}
+		return v, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] < unicode.MaxASCII && (unicode.IsLetter(p.input[p.pos]) || unicode.IsDigit(p.input[p.pos])) {
+		p.pos++
+	}
+	word := string(p.input[start:p.pos])
+	if word == "" {
+		return nil, p.errorf("expected a value but found %q", p.input[p.pos])
+	}
+
+	// A word followed by a bracket is a function, otherwise it is a number
+	p.skipSpaces()
+	if word == "mersenne" && p.pos < len(p.input) && p.input[p.pos] == '(' {
+		p.pos++
+		k, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(')'); !ok {
+			return nil, p.errorf("expected )")
+		}
+		if k.Sign() < 0 || !k.IsInt64() || k.Int64() > maxExpressionBits {
+			p.pos = start
+			return nil, p.errorf("mersenne(%s) is out of range, k must be between 0 and %d", k, maxExpressionBits)
+		}
+		m := new(big.Int).Lsh(oneBig, uint(k.Int64()))
+		return m.Sub(m, oneBig), nil
+	}
+
+	var v *big.Int
+	ok := false
+	if p.base == 0 {
+		v, ok = parsePrefixed(word)
+	} else {
+		v, ok = new(big.Int).SetString(word, p.base)
+	}
+	if !ok {
+		p.pos = start
+		if p.base == 0 {
+			return nil, p.errorf("%s is not a number", word)
+		}
+		return nil, p.errorf("%s is not a number in base %d", word, p.base)
+	}
+	return v, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestEvalExpression(t *testing.T) {
+	pow := func(b int64, e int64) *big.Int {
+		return new(big.Int).Exp(big.NewInt(b), big.NewInt(e), nil)
+	}
+	for _, c := range []struct {
+		expr string
+		base int
+		want *big.Int
+	}{
+		{"27", 10, big.NewInt(27)},
+		{"2^100+1", 10, new(big.Int).Add(pow(2, 100), oneBig)},
+		{"27*3^50", 10, new(big.Int).Mul(big.NewInt(27), pow(3, 50))},
+		{"2^3^2", 10, big.NewInt(512)},
+		{"-2^2", 10, big.NewInt(-4)},
+		{"(1+2)*(3+4)", 10, big.NewInt(21)},
+		{"10−3", 10, big.NewInt(7)},
+		{"5!", 10, big.NewInt(120)},
+		{"3!^2", 10, big.NewInt(36)},
+		{"2^3!", 10, big.NewInt(64)},
+		{"0!", 10, big.NewInt(1)},
+		{"mersenne(7)", 10, big.NewInt(127)},
+		{"mersenne(2+3)*2", 10, big.NewInt(62)},
+		{" 1 + 1 ", 10, big.NewInt(2)},
+		{"ff+1", 16, big.NewInt(256)},
+		{"11^10", 2, big.NewInt(9)},
+		{"0x10+0b11+0o7+9", 0, big.NewInt(35)},
+		{"mersenne(z)", 36, big.NewInt(1<<35 - 1)},
+		{"2^524288", 10, pow(2, 524288)},
+	} {
+		got, err := evalExpression(c.expr, c.base)
+		if err != nil {
+			t.Errorf("evalExpression(%q, %d) failed: %v", c.expr, c.base, err)
+			continue
+		}
+		if got.Cmp(c.want) != 0 {
+			t.Errorf("evalExpression(%q, %d) = %s, expected %s", c.expr, c.base, got, c.want)
+		}
+	}
+}
+
+func TestEvalExpressionErrors(t *testing.T) {
+	for _, c := range []struct {
+		expr string
+		base int
+		pos  int
+		msg  string
+	}{
+		{"", 10, 1, "expected a value"},
+		{"2^", 10, 3, "expected a value"},
+		{"2^^3", 10, 3, "expected a value"},
+		{"(1+2", 10, 5, "expected )"},
+		{"1+2)", 10, 4, "unexpected"},
+		{"12x+1", 10, 1, "12x is not a number in base 10"},
+		{"1+12x", 10, 3, "12x is not a number in base 10"},
+		{"2^-1", 10, 3, "negative"},
+		{"2^2000000", 10, 3, "larger than"},
+		{"3^1048576", 10, 3, "larger than"},
+		{"(0-1)!", 10, 6, "factorial"},
+		{"100000!", 10, 7, "factorial"},
+		{"mersenne(0-1)", 10, 1, "out of range"},
+		{"mersenne", 10, 1, "not a number"},
+		{"0x", 0, 1, "not a number"},
+	} {
+		_, err := evalExpression(c.expr, c.base)
+		e, ok := err.(*exprError)
+		if !ok {
+			t.Errorf("evalExpression(%q, %d) gave %v, expected an error at position %d", c.expr, c.base, err, c.pos)
+			continue
+		}
+		if e.pos != c.pos || !strings.Contains(e.msg, c.msg) {
+			t.Errorf("evalExpression(%q, %d) gave %q at position %d, expected %q at position %d", c.expr, c.base, e.msg, e.pos, c.msg, c.pos)
+		}
+	}
+}
+
+func TestEvalPositive(t *testing.T) {
+	for _, s := range []string{"0", "1-1", "-5", "2-3^2", "0x0"} {
+		_, err := evalPositive(s, 0)
+		if e, ok := err.(*exprError); !ok || e.pos != 1 || !strings.Contains(e.msg, "less than 1") {
+			t.Errorf("evalPositive(%q) gave %v, expected a value less than 1 at position 1", s, err)
+		}
+	}
+	if _, err := evalPositive("1+", 10); err == nil || !strings.Contains(err.Error(), "position 3") {
+		t.Errorf("evalPositive(\"1+\") gave %v, expected the fault in the expression", err)
+	}
+	if v, err := evalPositive("2^64-1", 10); err != nil || v.String() != "18446744073709551615" {
+		t.Errorf("evalPositive(\"2^64-1\") = %v, %v", v, err)
+	}
+}
+
+func TestEvalNonNegative(t *testing.T) {
+	if v, err := evalNonNegative("3-3", 10); err != nil || v.Sign() != 0 {
+		t.Errorf("evalNonNegative(\"3-3\") = %v, %v", v, err)
+	}
+	if _, err := evalNonNegative("2-3", 10); err == nil || !strings.Contains(err.Error(), "less than 0") {
+		t.Errorf("evalNonNegative(\"2-3\") gave %v, expected a value less than 0", err)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/big"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+// heatmapLayout places the values of a range on a grid
+type heatmapLayout int
+
+const (
+	rowMajorLayout heatmapLayout = iota // the values in order, width to a row
+	moduloLayout                        // n mod width across and n div width down
+)
+
+var heatmapLayoutNames = []string{"Row-major", "Mod m"}
+var heatmapColourNames = []string{"Stopping Time", "Steps"}
+
+// The largest side of a heatmap raster. A larger grid is drawn with each pixel
+// the mean of a block of cells.
+const maxHeatmapSide = 2048
+
+// The largest value a float64 holds exactly, beyond which values cannot be placed on the grid
+const maxExactFloat = 1 << 53
+
+// The grid of the last range run and how the heatmap is drawn. The layout and
+// width are read when a run starts, as its grid is laid out then.
+var heatmapState struct {
+	sync.Mutex
+	grid           *heatmapGrid
+	tooLarge       bool
+	layout         heatmapLayout
+	width          int64
+	byStoppingTime bool
+}
+
+var heatmapCanvas *canvas.Image
+var heatmapCaption *widget.Label
+
+// heatmapCell returns the column and row of n. The rows start at the row of lower.
+func heatmapCell(n int64, lower int64, width int64, layout heatmapLayout) (int64, int64) {
+	if layout == moduloLayout {
+		return n % width, n/width - lower/width
+	}
+	return (n - lower) % width, (n - lower) / width
+}
+
+// heatmapGrid bins the results of a run into the pixels of its heatmap as they
+// arrive, keeping the sums of each pixel rather than the results. The grid is
+// width cells across, and each pixel is a block of scaleX × scaleY cells.
+type heatmapGrid struct {
+	low           int64
+	high          int64
+	width         int64
+	layout        heatmapLayout
+	cols          int64
+	rows          int64
+	scaleX        int64
+	scaleY        int64
+	pw            int64
+	count         int64
+	counts        []uint32
+	steps         []float32
+	stoppingTimes []float32
+	stepsRange    [2]float64
+	stoppingRange [2]float64
+}
+
+// newHeatmapGrid lays out a grid for the values from low to high, both included
+func newHeatmapGrid(low int64, high int64, layout heatmapLayout, width int64) *heatmapGrid {
+	g := &heatmapGrid{low: low, high: high, width: width, layout: layout, cols: width}
+	if layout == rowMajorLayout && high-low+1 < width {
+		g.cols = high - low + 1
+	}
+	_, lastRow := heatmapCell(high, low, width, layout)
+	g.rows = lastRow + 1
+
+	g.scaleX = (g.cols + maxHeatmapSide - 1) / maxHeatmapSide
+	g.scaleY = (g.rows + maxHeatmapSide - 1) / maxHeatmapSide
+	g.pw = (g.cols + g.scaleX - 1) / g.scaleX
+	ph := (g.rows + g.scaleY - 1) / g.scaleY
+	g.counts = make([]uint32, g.pw*ph)
+	g.steps = make([]float32, g.pw*ph)
+	g.stoppingTimes = make([]float32, g.pw*ph)
+	return g
+}
+
+// add bins the result of n. A value off the grid is dropped.
+func (g *heatmapGrid) add(n int64, steps float64, stoppingTime float64) {
+	if n < g.low || n > g.high {
+		return
+	}
+	x, y := heatmapCell(n, g.low, g.width, g.layout)
+	idx := (y/g.scaleY)*g.pw + x/g.scaleX
+	g.counts[idx]++
+	g.steps[idx] += float32(steps)
+	g.stoppingTimes[idx] += float32(stoppingTime)
+
+	if g.count == 0 {
+		g.stepsRange = [2]float64{steps, steps}
+		g.stoppingRange = [2]float64{stoppingTime, stoppingTime}
+	}
+	g.stepsRange = [2]float64{math.Min(g.stepsRange[0], steps), math.Max(g.stepsRange[1], steps)}
+	g.stoppingRange = [2]float64{math.Min(g.stoppingRange[0], stoppingTime), math.Max(g.stoppingRange[1], stoppingTime)}
+	g.count++
+}
+
+// image colours each pixel by the mean of its cells with the viridis palette,
+// scaled from the least to the greatest result. Empty pixels are transparent.
+func (g *heatmapGrid) image(byStoppingTime bool) *image.NRGBA {
+	sums, vrange := g.steps, g.stepsRange
+	if byStoppingTime {
+		sums, vrange = g.stoppingTimes, g.stoppingRange
+	}
+	vmin, vmax := vrange[0], vrange[1]
+	if vmax == vmin {
+		vmax = vmin + 1
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, int(g.pw), len(g.counts)/int(g.pw)))
+	for idx, count := range g.counts {
+		if count == 0 {
+			continue
+		}
+		c := chart.Viridis(float64(sums[idx]/float32(count)), vmin, vmax)
+		copy(img.Pix[idx*4:], []uint8{c.R, c.G, c.B, 0xff})
+	}
+	return img
+}
+
+// startHeatmap lays out the grid for a run from the smallest and largest of
+// its values. The width is the square root of the count, so the grid is
+// square, unless one is given.
+func startHeatmap(tracker *sweepTracker) {
+	heatmapState.Lock()
+	defer heatmapState.Unlock()
+
+	heatmapState.grid = nil
+	heatmapState.tooLarge = false
+	if tracker.total == 0 {
+		return
+	}
+	low, high := tracker.bounds()
+	if high.Cmp(big.NewInt(maxExactFloat)) > 0 {
+		heatmapState.tooLarge = true
+		return
+	}
+	width := heatmapState.width
+	if width == 0 {
+		width = int64(math.Ceil(math.Sqrt(float64(tracker.total))))
+	}
+	heatmapState.grid = newHeatmapGrid(low.Int64(), high.Int64(), heatmapState.layout, width)
+}
+
+// addHeatmapResult bins a result of the run into the heatmap
+func addHeatmapResult(report sequenceProgress) {
+	heatmapState.Lock()
+	defer heatmapState.Unlock()
+
+	if heatmapState.grid != nil {
+		heatmapState.grid.add(report.number.Int64(), float64(report.steps), float64(report.stoppingTime))
+	}
+}
+
+// refreshHeatmap draws the heatmap of the last range run
+func refreshHeatmap() {
+	heatmapState.Lock()
+	var img *image.NRGBA
+	caption := ""
+	switch g := heatmapState.grid; {
+	case g != nil && g.count > 0:
+		img = g.image(heatmapState.byStoppingTime)
+		caption = fmt.Sprintf("%d values on a grid of %d × %d", g.count, g.cols, g.rows)
+		if g.scaleX > 1 || g.scaleY > 1 {
+			caption += fmt.Sprintf(", each pixel the mean of %d × %d cells", g.scaleX, g.scaleY)
+		}
+	case heatmapState.tooLarge:
+		caption = "The values are too large to place on a grid"
+	}
+	heatmapState.Unlock()
+
+	updateUI(func() {
+		heatmapCaption.SetText(caption)
+		if img == nil {
+			heatmapCanvas.Hide()
+			return
+		}
+		heatmapCanvas.Image = img
+		heatmapCanvas.Show()
+		heatmapCanvas.Refresh()
+	})
+}
+
+// makeHeatmapTab shows the values of the last range run laid out on a grid
+// and coloured by their stopping time or steps
+func makeHeatmapTab(win fyne.Window) fyne.CanvasObject {
+
+	layoutSelect := widget.NewSelect(heatmapLayoutNames, nil)
+	layoutSelect.SetSelected(heatmapLayoutNames[0])
+
+	widthEntry := widget.NewEntry()
+	widthEntry.SetPlaceHolder("Auto")
+
+	colourSelect := widget.NewSelect(heatmapColourNames, nil)
+	colourSelect.SetSelected(heatmapColourNames[0])
+
+	heatmapCanvas = &canvas.Image{FillMode: canvas.ImageFillContain, ScaleMode: canvas.ImageScalePixels}
+	heatmapCanvas.SetMinSize(fyne.NewSize(400, 400))
+	heatmapCanvas.Hide()
+	heatmapCaption = widget.NewLabel("")
+
+	// The options are read here, on the UI goroutine, and the heatmap drawn from them
+	drawBtn := widget.NewButton("Draw", func() {
+		var width int64
+		if text := removeSpaces(widthEntry.Text); text != "" {
+			v, ok := checkValidation(text, "Base 10", win)
+			if !ok {
+				return
+			}
+			if v.Sign() <= 0 || !v.IsInt64() {
+				showInformation("Number Format Error", "The width must be at least 1 and fit in 64 bits", win)
+				return
+			}
+			width = v.Int64()
+		}
+
+		heatmapState.Lock()
+		layout := heatmapLayout(layoutSelect.SelectedIndex())
+		relaid := heatmapState.grid != nil && (layout != heatmapState.layout || width != heatmapState.width)
+		heatmapState.layout = layout
+		heatmapState.width = width
+		heatmapState.byStoppingTime = colourSelect.Selected == heatmapColourNames[0]
+		heatmapState.Unlock()
+
+		// The results are binned as they arrive, so only the colours of the last run can be drawn again
+		if relaid {
+			showInformation("Heatmap", "The layout and width are used from the next run", win)
+		}
+		go refreshHeatmap()
+	})
+
+	heatmapState.Lock()
+	heatmapState.layout = rowMajorLayout
+	heatmapState.width = 0
+	heatmapState.byStoppingTime = true
+	heatmapState.Unlock()
+
+	form := widget.NewForm(
+		widget.NewFormItem(fmt.Sprintf("%15s", "Layout:"), layoutSelect),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Width or m:"), widthEntry),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Colour By:"), colourSelect),
+	)
+	return container.NewBorder(container.NewVBox(form, drawBtn), heatmapCaption, nil, nil, heatmapCanvas)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+// viridisAt returns the colour a heatmap gives v
+func viridisAt(v float64, vmin float64, vmax float64) color.NRGBA {
+	c := chart.Viridis(v, vmin, vmax)
+	return color.NRGBA{R: c.R, G: c.G, B: c.B, A: 0xff}
+}
+
+// gridOf bins the values from low to high, each with the steps result gives it
+func gridOf(low int64, high int64, layout heatmapLayout, width int64, result func(n int64) float64) *heatmapGrid {
+	g := newHeatmapGrid(low, high, layout, width)
+	for n := low; n <= high; n++ {
+		g.add(n, result(n), 0)
+	}
+	return g
+}
+
+func TestHeatmapLayouts(t *testing.T) {
+	tens := func(n int64) float64 {
+		return float64(n * 10)
+	}
+
+	for _, c := range []struct {
+		layout     heatmapLayout
+		cols, rows int64
+		cells      map[[2]int]float64
+	}{
+		// 5 to 14 in rows of four from 5
+		{rowMajorLayout, 4, 3, map[[2]int]float64{{0, 0}: 5, {3, 0}: 8, {0, 1}: 9, {1, 2}: 14}},
+		// 5 to 14 by n mod 4 across and n div 4 down, from the row of 4
+		{moduloLayout, 4, 3, map[[2]int]float64{{1, 0}: 5, {3, 0}: 7, {0, 1}: 8, {2, 2}: 14}},
+	} {
+		g := gridOf(5, 14, c.layout, 4, tens)
+		img := g.image(false)
+		if g.cols != c.cols || g.rows != c.rows || g.scaleX != 1 || g.scaleY != 1 || int64(img.Bounds().Dx()) != g.cols || int64(img.Bounds().Dy()) != g.rows {
+			t.Errorf("%s: a %d × %d grid at scale %d × %d in a %v image, expected %d × %d", heatmapLayoutNames[c.layout], g.cols, g.rows, g.scaleX, g.scaleY, img.Bounds(), c.cols, c.rows)
+			continue
+		}
+		for cell, n := range c.cells {
+			if got, want := img.NRGBAAt(cell[0], cell[1]), viridisAt(n*10, 50, 140); got != want {
+				t.Errorf("%s: cell %v is %v, expected the colour of %v", heatmapLayoutNames[c.layout], cell, got, n)
+			}
+		}
+	}
+
+	// The cells before 5 in the modulo layout are left empty, as is a value off the grid
+	g := gridOf(5, 14, moduloLayout, 4, tens)
+	g.add(15, 1000, 0)
+	if img := g.image(false); img.NRGBAAt(0, 0).A != 0 || g.count != 10 {
+		t.Errorf("the cell of 4 is coloured or 15 was binned into a grid of %d values", g.count)
+	}
+}
+
+func TestHeatmapScalesLargeGrids(t *testing.T) {
+	// One row too wide for the raster is drawn three cells to a pixel, each the mean of its cells
+	side := int64(3 * maxHeatmapSide)
+	g := gridOf(0, side-1, rowMajorLayout, side, func(n int64) float64 {
+		return float64(n % 2)
+	})
+	if g.cols != side || g.rows != 1 || g.scaleX != 3 || g.scaleY != 1 {
+		t.Fatalf("a %d × %d grid at scale %d × %d, expected %d × 1 at scale 3 × 1", g.cols, g.rows, g.scaleX, g.scaleY, side)
+	}
+	img := g.image(false)
+	if img.Bounds().Dx() != maxHeatmapSide || img.Bounds().Dy() != 1 {
+		t.Errorf("the image is %v", img.Bounds())
+	}
+	if got, want := img.NRGBAAt(0, 0), viridisAt(1.0/3, 0, 1); got != want {
+		t.Errorf("the first pixel is %v, expected the colour of 1/3", got)
+	}
+
+	// Colouring by stopping time uses the stopping times binned with the steps
+	if got := g.image(true).NRGBAAt(0, 0); got != viridisAt(0, 0, 1) {
+		t.Errorf("the first pixel by stopping time is %v, expected the colour of 0", got)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+// The points the heuristic curve of the sequence length chart is drawn with
+const heuristicCurvePoints = 100
+
+// expectedStepsPerBit is the steps a value takes to reach 1 for each bit of
+// it, in the heuristic where each step is odd or even at random. A step of the
+// shortcut map multiplies by 3/2 or 1/2 equally often, log2(3)/2 - 1 bits a
+// step on average, and the standard map takes an extra step for each odd one.
+// The standard map's 7.23 steps a bit is the 10.43 · ln n often quoted, which
+// is for the natural log of n, not log2 n.
+func expectedStepsPerBit(m collatzMap) float64 {
+	shortcutSteps := 2 / (2 - math.Log2(3))
+	if m == shortcutMap {
+		return shortcutSteps
+	}
+	return 1.5 * shortcutSteps
+}
+
+// expectedDrift is the bits a stone changes by each step in the heuristic, an
+// odd and an even step together multiplying it by about 3/4
+func expectedDrift(m collatzMap) float64 {
+	return -1 / expectedStepsPerBit(m)
+}
+
+// expectedOddShare is the share of the steps that are odd in the heuristic
+func expectedOddShare(m collatzMap) float64 {
+	if m == shortcutMap {
+		return 0.5
+	}
+	return 1.0 / 3
+}
+
+// trajectoryDeviation is how far a trajectory strays from the heuristic
+type trajectoryDeviation struct {
+	expectedSteps    float64
+	stepsDeviation   float64 // the steps taken less those expected
+	oddShare         float64
+	expectedOddShare float64
+	oddSigma         float64 // the odd steps less those expected, in standard deviations
+	drift            float64 // the bits the stones fall each step on average
+	expectedDrift    float64
+}
+
+// newTrajectoryDeviation compares a finished trajectory with the heuristic.
+// Its steps are the odd or even steps of the shortcut map, each taken at random,
+// so the number that are odd is binomial.
+func newTrajectoryDeviation(report *sequenceProgress) trajectoryDeviation {
+	m := report.trajectory.mapping
+	bits := log2Big(report.number)
+	d := trajectoryDeviation{
+		expectedSteps:    expectedStepsPerBit(m) * bits,
+		expectedOddShare: expectedOddShare(m),
+		expectedDrift:    expectedDrift(m),
+	}
+	d.stepsDeviation = float64(report.steps) - d.expectedSteps
+	if report.steps == 0 {
+		return d
+	}
+
+	d.oddShare = float64(report.upMoves) / float64(report.steps)
+	d.drift = -bits / float64(report.steps)
+	shortcutSteps := float64(report.downMoves)
+	if m == shortcutMap {
+		shortcutSteps = float64(report.steps)
+	}
+	d.oddSigma = (float64(report.upMoves) - shortcutSteps/2) / (math.Sqrt(shortcutSteps) / 2)
+	return d
+}
+
+// heuristicCurve is the steps the heuristic expects of the values from lo to
+// hi, c · ln n for c steps a bit of n over ln 2
+func heuristicCurve(lo float64, hi float64, m collatzMap) chart.Series {
+	c := expectedStepsPerBit(m) / math.Ln2
+	var xV, yV []float64
+	for i := 0; i <= heuristicCurvePoints; i++ {
+		x := lo + (hi-lo)*float64(i)/heuristicCurvePoints
+		xV = append(xV, x)
+		yV = append(yV, c*math.Log(math.Max(x, 1)))
+	}
+	return chart.ContinuousSeries{
+		Name:    "Heuristic",
+		Style:   chart.Style{StrokeColor: chart.ColorRed, StrokeWidth: 2},
+		XValues: xV,
+		YValues: yV,
+	}
+}
+
+// driftLine is log2 of a stone that starts at 2^start and drifts as the
+// heuristic expects, from step lo to step hi. It stops falling at 1.
+func driftLine(start float64, lo float64, hi float64, m collatzMap) chart.ContinuousSeries {
+	drift := expectedDrift(m)
+	at := func(x float64) float64 {
+		return math.Max(0, start+drift*x)
+	}
+
+	xV, yV := []float64{lo}, []float64{at(lo)}
+	if end := -start / drift; end > lo && end < hi {
+		xV, yV = append(xV, end), append(yV, 0)
+	}
+	xV, yV = append(xV, hi), append(yV, at(hi))
+	return chart.ContinuousSeries{
+		Name:    "Drift",
+		Style:   chart.Style{StrokeColor: chart.ColorRed, StrokeWidth: 2},
+		XValues: xV,
+		YValues: yV,
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+func TestTrajectoryDeviation(t *testing.T) {
+	// 27 takes 111 steps, 41 of them odd, where the heuristic expects 7.228 · log2 27
+	report := Collatz(*big.NewInt(27), standardMap, nil, 0)
+	d := newTrajectoryDeviation(&report)
+	near := func(got float64, want float64) bool {
+		return math.Abs(got-want) < 0.001
+	}
+	if !near(d.expectedSteps, 34.370) || !near(d.stepsDeviation, 76.630) {
+		t.Errorf("%.3f steps expected, %.3f more taken, expected 34.370 and 76.630", d.expectedSteps, d.stepsDeviation)
+	}
+	// 41 odd of 70 steps of the shortcut map, where 35 ± 4.183 are expected
+	if !near(d.oddShare, 41.0/111) || !near(d.expectedOddShare, 1.0/3) || !near(d.oddSigma, 1.434) {
+		t.Errorf("%.3f of the steps are odd, %.3fσ from the %.3f expected", d.oddShare, d.oddSigma, d.expectedOddShare)
+	}
+	if !near(d.drift, -math.Log2(27)/111) || !near(d.expectedDrift, -0.138) {
+		t.Errorf("the drift is %.4f a step, expected %.4f", d.drift, d.expectedDrift)
+	}
+
+	// The shortcut map counts every step, half of them odd
+	report = Collatz(*big.NewInt(27), shortcutMap, nil, 0)
+	d = newTrajectoryDeviation(&report)
+	if !near(d.expectedOddShare, 0.5) || !near(d.oddSigma, (41-35)/(math.Sqrt(70)/2)) {
+		t.Errorf("%.3fσ from the %.3f expected", d.oddSigma, d.expectedOddShare)
+	}
+
+	// 1 takes no steps, as expected
+	report = Collatz(*big.NewInt(1), standardMap, nil, 0)
+	if d := newTrajectoryDeviation(&report); d.expectedSteps != 0 || d.stepsDeviation != 0 || d.drift != 0 {
+		t.Errorf("the deviation of 1 is %+v", d)
+	}
+}
+
+func TestDriftLine(t *testing.T) {
+	// From 2^10 the standard map is expected to reach 1 in 72.28 steps
+	line := driftLine(10, 0, 100, standardMap)
+	if len(line.XValues) != 3 || line.YValues[0] != 10 || math.Abs(line.XValues[1]-72.283) > 0.001 || line.YValues[1] != 0 || line.YValues[2] != 0 {
+		t.Errorf("the line is %v, %v, expected 10 falling to 0 at 72.28", line.XValues, line.YValues)
+	}
+	// Zoomed in before it reaches 1 it is a single segment
+	line = driftLine(10, 10, 20, standardMap)
+	if len(line.XValues) != 2 || math.Abs(line.YValues[1]-(10-20/expectedStepsPerBit(standardMap))) > 1e-9 {
+		t.Errorf("the line is %v, %v", line.XValues, line.YValues)
+	}
+
+	// The curve of the sequence length chart is 10.43 · ln n
+	curve := heuristicCurve(1, 1000, standardMap).(chart.ContinuousSeries)
+	if n := len(curve.XValues); n != heuristicCurvePoints+1 || curve.YValues[0] != 0 || math.Abs(curve.YValues[n-1]-10.428*math.Log(1000)) > 0.01 {
+		t.Errorf("the curve runs from %.2f to %.2f", curve.YValues[0], curve.YValues[n-1])
+	}
+}
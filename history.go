@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// The preference the history is kept in and how many entries that are not
+// bookmarked it keeps
+const historyKey = "history"
+const maxHistoryEntries = 100
+
+// historyEntry is a value calculated on the Single Value tab, with the input
+// as it was typed so it can be calculated again
+type historyEntry struct {
+	Input      string    `json:"input"`
+	Base       string    `json:"base"`
+	Number     string    `json:"n"`
+	Steps      int       `json:"steps"`
+	MaxStone   string    `json:"maxStone"`
+	Bookmarked bool      `json:"bookmarked,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	Time       time.Time `json:"time"`
+}
+
+// calcHistory lists the values calculated, newest first, and is saved to the
+// app preferences on every change so it lasts between sessions. Each value is
+// listed once, under the last input that gave it.
+type calcHistory struct {
+	sync.Mutex
+	prefs   fyne.Preferences
+	win     fyne.Window
+	entries []historyEntry
+}
+
+var singleHistory *calcHistory
+
+// The history sidebar and the rows it shows
+var historyList *widget.List
+var historyRows tableRows[historyEntry]
+var historyBookmarksOnly atomic.Bool
+
+// Star icons for bookmarks, from the Material icon set Fyne's own icons come from
+var bookmarkedIcon = theme.NewThemedResource(fyne.NewStaticResource("star.svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24"><path d="M12 17.27L18.18 21l-1.64-7.03L22 9.24l-7.19-.61L12 2 9.19 8.63 2 9.24l5.46 4.73L5.82 21z"/></svg>`)))
+var notBookmarkedIcon = theme.NewThemedResource(fyne.NewStaticResource("star_border.svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="24" height="24" viewBox="0 0 24 24"><path d="M22 9.24l-7.19-.62L12 2 9.19 8.63 2 9.24l5.46 4.73L5.82 21 12 17.27 18.18 21l-1.63-7.03L22 9.24zM12 15.4l-3.76 2.27 1-4.28-3.32-2.88 4.38-.38L12 6.1l1.71 4.04 4.38.38-3.32 2.88 1 4.28L12 15.4z"/></svg>`)))
+
+// loadHistory reads the history from the preferences, starting afresh if it
+// cannot be read. Errors are shown over win.
+func loadHistory(prefs fyne.Preferences, win fyne.Window) *calcHistory {
+	h := &calcHistory{prefs: prefs, win: win}
+	if s := prefs.String(historyKey); s != "" {
+		if err := json.Unmarshal([]byte(s), &h.entries); err != nil {
+			showInformation("History Error", fmt.Sprintf("Unable to read the calculation history: %v", err), win)
+			h.entries = nil
+		}
+	}
+	return h
+}
+
+// Add puts a calculation at the top of the history, keeping the bookmark and
+// note of an earlier calculation of the same value
+func (h *calcHistory) Add(e historyEntry) {
+	h.Lock()
+	defer h.Unlock()
+
+	if i := h.find(e.Number); i >= 0 {
+		e.Bookmarked = h.entries[i].Bookmarked
+		e.Note = h.entries[i].Note
+		h.entries = append(h.entries[:i], h.entries[i+1:]...)
+	}
+	h.entries = append([]historyEntry{e}, h.entries...)
+
+	// Drop the oldest entries beyond the limit, unless they are bookmarked
+	kept := h.entries[:0]
+	unmarked := 0
+	for _, entry := range h.entries {
+		if !entry.Bookmarked {
+			unmarked++
+			if unmarked > maxHistoryEntries {
+				continue
+			}
+		}
+		kept = append(kept, entry)
+	}
+	h.entries = kept
+
+	h.save()
+}
+
+// Bookmark stars or unstars a value
+func (h *calcHistory) Bookmark(number string, bookmarked bool) {
+	h.Lock()
+	defer h.Unlock()
+
+	if i := h.find(number); i >= 0 {
+		h.entries[i].Bookmarked = bookmarked
+		h.save()
+	}
+}
+
+// SetNote sets the note kept with a value
+func (h *calcHistory) SetNote(number string, note string) {
+	h.Lock()
+	defer h.Unlock()
+
+	if i := h.find(number); i >= 0 {
+		h.entries[i].Note = note
+		h.save()
+	}
+}
+
+// Remove takes a value out of the history
+func (h *calcHistory) Remove(number string) {
+	h.Lock()
+	defer h.Unlock()
+
+	if i := h.find(number); i >= 0 {
+		h.entries = append(h.entries[:i], h.entries[i+1:]...)
+		h.save()
+	}
+}
+
+// Entries returns a copy of the history, or of the bookmarks only
+func (h *calcHistory) Entries(bookmarksOnly bool) []historyEntry {
+	h.Lock()
+	defer h.Unlock()
+
+	var entries []historyEntry
+	for _, e := range h.entries {
+		if e.Bookmarked || !bookmarksOnly {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func (h *calcHistory) find(number string) int {
+	for i, e := range h.entries {
+		if e.Number == number {
+			return i
+		}
+	}
+	return -1
+}
+
+// save writes the history to the preferences, with the lock held
+func (h *calcHistory) save() {
+	b, err := json.Marshal(h.entries)
+	if err != nil {
+		showInformation("History Error", fmt.Sprintf("Unable to save the calculation history: %v", err), h.win)
+		return
+	}
+	h.prefs.SetString(historyKey, string(b))
+}
+
+// makeHistoryPanel lists the values calculated before. Selecting one puts its
+// input back in the entries and calculates it again.
+func makeHistoryPanel(entryBase *widget.Select, entryValue *widget.Entry, win fyne.Window) fyne.CanvasObject {
+
+	historyList = widget.NewList(
+		func() int {
+			return historyRows.Len()
+		},
+		func() fyne.CanvasObject {
+			star := widget.NewButtonWithIcon("", notBookmarkedIcon, nil)
+			star.Importance = widget.LowImportance
+			note := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), nil)
+			note.Importance = widget.LowImportance
+			remove := widget.NewButtonWithIcon("", theme.DeleteIcon(), nil)
+			remove.Importance = widget.LowImportance
+			return container.NewBorder(nil, nil, star, container.NewHBox(note, remove), widget.NewLabel("Template Input\nTemplate Summary"))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			row := item.(*fyne.Container)
+			label := row.Objects[0].(*widget.Label)
+			star := row.Objects[1].(*widget.Button)
+			buttons := row.Objects[2].(*fyne.Container)
+			note := buttons.Objects[0].(*widget.Button)
+			remove := buttons.Objects[1].(*widget.Button)
+
+			e, ok := historyRows.Row(id)
+			if !ok {
+				label.SetText("")
+				return
+			}
+			label.SetText(historyText(e))
+			if e.Bookmarked {
+				star.SetIcon(bookmarkedIcon)
+			} else {
+				star.SetIcon(notBookmarkedIcon)
+			}
+			star.OnTapped = func() {
+				singleHistory.Bookmark(e.Number, !e.Bookmarked)
+				showHistory()
+			}
+			note.OnTapped = func() {
+				editHistoryNote(e, win)
+			}
+			remove.OnTapped = func() {
+				singleHistory.Remove(e.Number)
+				showHistory()
+			}
+		})
+	historyList.OnSelected = func(id widget.ListItemID) {
+		historyList.Unselect(id)
+		e, ok := historyRows.Row(id)
+		if !ok {
+			return
+		}
+		updateUI(func() {
+			entryBase.SetSelected(e.Base)
+			entryValue.SetText(e.Input)
+		})
+		go calcStones(e.Input, e.Base, win)
+	}
+
+	singleHistory = loadHistory(fyne.CurrentApp().Preferences(), win)
+	historyRows.Set(singleHistory.Entries(historyBookmarksOnly.Load()))
+
+	bookmarksOnly := widget.NewCheck("Bookmarks only", nil)
+	bookmarksOnly.SetChecked(historyBookmarksOnly.Load())
+	bookmarksOnly.OnChanged = func(on bool) {
+		historyBookmarksOnly.Store(on)
+		showHistory()
+	}
+
+	heading := container.NewHBox(widget.NewRichTextFromMarkdown("**History**"), layout.NewSpacer(), bookmarksOnly)
+	return container.NewBorder(heading, nil, nil, nil, historyList)
+}
+
+// historyText describes an entry on two lines, the input and its note then the result
+func historyText(e historyEntry) string {
+	input := abbreviate(e.Input)
+	if e.Base != "Base 10" {
+		input += fmt.Sprintf(" (%s)", e.Base)
+	}
+	if e.Note != "" {
+		input += " - " + strings.Join(strings.Fields(e.Note), " ")
+	}
+	return fmt.Sprintf("%s\n%d steps, max stone %s", input, e.Steps, abbreviate(formatDecimal(e.MaxStone)))
+}
+
+// showHistory lists the history, or the bookmarks only, in the sidebar
+func showHistory() {
+	historyRows.Set(singleHistory.Entries(historyBookmarksOnly.Load()))
+	updateUI(historyList.Refresh)
+}
+
+// editHistoryNote asks for the note kept with an entry
+func editHistoryNote(e historyEntry, win fyne.Window) {
+	updateUI(func() {
+		note := widget.NewMultiLineEntry()
+		note.SetText(e.Note)
+		dialog.ShowForm("Note for "+abbreviate(e.Input), "Save", "Cancel", []*widget.FormItem{
+			widget.NewFormItem("Note", note),
+		}, func(save bool) {
+			if save {
+				singleHistory.SetNote(e.Number, note.Text)
+				showHistory()
+			}
+		}, win)
+	})
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestHistoryKeepsBookmarks(t *testing.T) {
+	a := test.NewApp()
+	defer a.Quit()
+
+	h := loadHistory(a.Preferences(), nil)
+	h.Add(historyEntry{Input: "27", Base: "Base 10", Number: "27", Steps: 111, MaxStone: "9232"})
+	h.Bookmark("27", true)
+	h.SetNote("27", "the famous one")
+
+	// Filling the history drops the oldest entries but not the bookmark
+	for i := 0; i < maxHistoryEntries+10; i++ {
+		n := fmt.Sprintf("%d", 1000+i)
+		h.Add(historyEntry{Input: n, Base: "Base 10", Number: n})
+	}
+	entries := h.Entries(false)
+	if len(entries) != maxHistoryEntries+1 {
+		t.Errorf("the history has %d entries, expected %d", len(entries), maxHistoryEntries+1)
+	}
+	if entries[0].Number != "1109" || entries[len(entries)-1].Number != "27" {
+		t.Errorf("the history runs from %s to %s", entries[0].Number, entries[len(entries)-1].Number)
+	}
+
+	// Calculating a value again moves it to the top and keeps its note
+	h.Add(historyEntry{Input: "0x1b", Base: "Auto", Number: "27", Steps: 111, MaxStone: "9232"})
+	bookmarks := h.Entries(true)
+	if len(bookmarks) != 1 || bookmarks[0].Input != "0x1b" || bookmarks[0].Note != "the famous one" {
+		t.Errorf("the bookmarks are %+v", bookmarks)
+	}
+	if h.Entries(false)[0].Number != "27" {
+		t.Error("the value calculated again is not at the top")
+	}
+
+	// The history is read back from the preferences
+	again := loadHistory(a.Preferences(), nil).Entries(false)
+	if len(again) != len(h.Entries(false)) || again[0] != h.Entries(false)[0] {
+		t.Error("the history did not survive a reload")
+	}
+
+	h.Remove("27")
+	if len(loadHistory(a.Preferences(), nil).Entries(true)) != 0 {
+		t.Error("the removed bookmark is still saved")
+	}
+}
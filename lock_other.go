@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// lockFile does nothing where there are no advisory locks, so only one
+// process at a time should write the results database there
+func lockFile(f *os.File) error {
+	return nil
+}
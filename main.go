@@ -1,21 +1,47 @@
 package main
 
 import (
-	"fyne.io/fyne/v2"
+	"fmt"
+	"os"
+
 	"fyne.io/fyne/v2/app"
 )
 
+const appID = "com.quaysystems.go.fyne.collatz"
+
 func main() {
-	a := app.NewWithID("com.quaysystems.go.fyne.collatz")
+	a := app.NewWithID(appID)
+
+	// Any arguments select a command line mode instead of the visualiser
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:], os.Stdout))
+	}
+
 	w := a.NewWindow("Collatz Visualisation")
 
+	// Put the saved settings into effect before anything uses them
+	applySettings(loadSettings(a.Preferences()))
+
+	// Open the database of previously computed values
+	store, err := openDefaultResultStore(false)
+	if err != nil {
+		showInformation("Database Error", fmt.Sprintf("Unable to open the results database, so results will not be kept: %v", err), w)
+	} else {
+		results = store
+		defer results.Close()
+	}
+
+	startWorkerPool(currentSettings().Workers)
+
+	// Serve metrics for monitoring long sweeps
+	startMetrics(metricsAddr, os.Stdout)
+
 	w.SetMaster()
 	w.SetContent(makeEntryTab(w))
 
 	// Create the go routines to update the UI
 	go handleSingleModeStatusReport()
-	go handleMultiModeStatusReport()
 
-	w.Resize(fyne.NewSize(900, 800))
+	restoreWindow(w)
 	w.ShowAndRun()
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The address the visualiser serves its metrics on
+var metricsAddr = "localhost:9464"
+
+// The number of values computed by the worker pool since start up
+var valuesProcessed atomic.Int64
+
+// throughputSampler measures the values processed per second once a second
+type throughputSampler struct {
+	sync.Mutex
+	last int64
+	rate float64
+	once sync.Once
+}
+
+var throughput = &throughputSampler{}
+
+func (t *throughputSampler) start() {
+	t.once.Do(func() {
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			for range ticker.C {
+				now := valuesProcessed.Load()
+				t.Lock()
+				t.rate = float64(now - t.last)
+				t.last = now
+				t.Unlock()
+			}
+		}()
+	})
+}
+
+func (t *throughputSampler) Rate() float64 {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.rate
+}
+
+// metricsHandler serves the metrics in the Prometheus text exposition format.
+// jobs may be nil when there is no API server.
+func metricsHandler(jobs func() []jobStatus) http.HandlerFunc {
+	throughput.start()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		var statuses []jobStatus
+		if jobs != nil {
+			statuses = jobs()
+		}
+		writeMetrics(w, statuses)
+	}
+}
+
+func writeMetrics(w io.Writer, jobs []jobStatus) {
+	metric := func(name string, kind string, help string, value float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+	}
+
+	metric("collatz_values_processed_total", "counter", "Values whose sequence has been computed by the worker pool.", float64(valuesProcessed.Load()))
+	metric("collatz_values_per_second", "gauge", "Values computed by the worker pool in the last second.", throughput.Rate())
+	metric("collatz_workers", "gauge", "Workers in the pool.", float64(workersThreadSafeSlice.Len()))
+
+	if session := currentRangeSession(); session != nil {
+		snap := session.Snapshot()
+		metric("collatz_queue_depth", "gauge", "Results waiting to be folded into the current Range tab run.", float64(snap.QueueDepth))
+		metric("collatz_highwater_steps", "gauge", "Longest sequence found by the current Range tab run.", float64(snap.HighwaterSteps))
+		metric("collatz_highwater_stone_bits", "gauge", "Bit length of the largest stone found by the current Range tab run.", float64(session.HighwaterStoneBits()))
+	}
+
+	if results != nil {
+		hits, misses := results.Stats()
+		ratio := 0.0
+		if hits+misses > 0 {
+			ratio = float64(hits) / float64(hits+misses)
+		}
+		metric("collatz_cache_hits_total", "counter", "Lookups answered by the results database.", float64(hits))
+		metric("collatz_cache_misses_total", "counter", "Lookups not found in the results database.", float64(misses))
+		metric("collatz_cache_hit_ratio", "gauge", "Fraction of lookups answered by the results database.", ratio)
+	}
+
+	if len(jobs) == 0 {
+		return
+	}
+	jobMetric := func(name string, help string, value func(jobStatus) float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, j := range jobs {
+			fmt.Fprintf(w, "%s{job=%q,status=%q} %g\n", name, j.ID, j.Status, value(j))
+		}
+	}
+	jobMetric("collatz_job_processed", "Values completed by an API range job.", func(j jobStatus) float64 { return float64(j.Processed) })
+	jobMetric("collatz_job_percent", "Percentage of an API range job completed.", func(j jobStatus) float64 { return j.Percent })
+	jobMetric("collatz_job_highwater_steps", "Longest sequence found by an API range job.", func(j jobStatus) float64 { return float64(j.HighwaterSteps) })
+	jobMetric("collatz_job_highwater_stone_bits", "Bit length of the largest stone found by an API range job.", func(j jobStatus) float64 { return float64(decimalBitLen(j.HighwaterStone)) })
+}
+
+func decimalBitLen(s string) int {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return 0
+	}
+	return n.BitLen()
+}
+
+// serveMetrics serves /metrics on addr for the visualiser and the headless workers
+func serveMetrics(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", metricsHandler(nil))
+	return http.ListenAndServe(addr, mux)
+}
+
+// startMetrics serves metrics on addr in the background, unless addr is empty
+func startMetrics(addr string, out io.Writer) {
+	if addr == "" {
+		return
+	}
+	go func() {
+		if err := serveMetrics(addr); err != nil {
+			fmt.Fprintf(out, "Unable to serve metrics on %s: %v\n", addr, err)
+		}
+	}()
+}
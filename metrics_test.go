@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// scrape returns the metrics served by a handler
+func scrape(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("the metrics returned %d with %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	return rec.Body.String()
+}
+
+// checkMetrics checks every line of want is among the metrics
+func checkMetrics(t *testing.T, metrics string, want ...string) {
+	t.Helper()
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(metrics, "\n") {
+		lines[line] = true
+	}
+	for _, line := range want {
+		if !lines[line] {
+			t.Errorf("the metrics do not have %q:\n%s", line, metrics)
+		}
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	startTestPool()
+	saved := results
+	t.Cleanup(func() {
+		results = saved
+	})
+	results = nil
+
+	metrics := scrape(t, metricsHandler(nil))
+	checkMetrics(t, metrics,
+		"# HELP collatz_values_processed_total Values whose sequence has been computed by the worker pool.",
+		"# TYPE collatz_values_processed_total counter",
+		"# TYPE collatz_values_per_second gauge",
+		"# TYPE collatz_workers gauge",
+		"collatz_workers 8",
+	)
+	if strings.Contains(metrics, "collatz_cache") || strings.Contains(metrics, "collatz_job") {
+		t.Errorf("there are metrics of a database and jobs that do not exist:\n%s", metrics)
+	}
+
+	// The database and the API jobs are included once there are any
+	store := openTestStore(t, filepath.Join(t.TempDir(), storeFile))
+	defer store.Close()
+	results = store
+	jobs := func() []jobStatus {
+		return []jobStatus{
+			{ID: "1", Status: sessionFinished, Processed: 99, Percent: 100, HighwaterSteps: 118, HighwaterStone: "9232"},
+			{ID: "2", Status: sessionRunning, Processed: 50, Percent: 12.5},
+		}
+	}
+	checkMetrics(t, scrape(t, metricsHandler(jobs)),
+		"collatz_cache_hits_total 0",
+		"collatz_cache_hit_ratio 0",
+		"# TYPE collatz_job_processed gauge",
+		`collatz_job_processed{job="1",status="finished"} 99`,
+		`collatz_job_processed{job="2",status="running"} 50`,
+		`collatz_job_percent{job="2",status="running"} 12.5`,
+		`collatz_job_highwater_steps{job="1",status="finished"} 118`,
+		`collatz_job_highwater_stone_bits{job="1",status="finished"} 14`,
+		`collatz_job_highwater_stone_bits{job="2",status="running"} 0`,
+	)
+}
+
+func TestServerMetrics(t *testing.T) {
+	server := startTestServer(t)
+	id := startRangeJob(t, server, `{"lower": "1", "upper": "100"}`)
+
+	var status jobStatus
+	waitUntil(t, "the job to finish", func() bool {
+		request(t, http.MethodGet, server.URL+"/jobs/"+id, "", &status)
+		return status.Status != sessionRunning
+	})
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	metrics, _ := io.ReadAll(resp.Body)
+	checkMetrics(t, string(metrics), `collatz_job_processed{job="`+id+`",status="finished"} 100`)
+}
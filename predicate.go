@@ -0,0 +1,360 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// predicateTerm is a quantity of a result, exactly as a fraction
+type predicateTerm func(r *sequenceProgress) (*big.Rat, error)
+
+// searchPredicate is a condition on the result of a value, compiled once and
+// tested by the workers on every result of a search
+type searchPredicate struct {
+	source string
+	test   func(r *sequenceProgress) bool
+}
+
+// The quantities of a result a predicate can refer to
+var predicateVariables = map[string]predicateTerm{
+	"n": func(r *sequenceProgress) (*big.Rat, error) {
+		return new(big.Rat).SetInt(r.number), nil
+	},
+	"steps": func(r *sequenceProgress) (*big.Rat, error) {
+		return new(big.Rat).SetInt64(int64(r.steps)), nil
+	},
+	"stopping": func(r *sequenceProgress) (*big.Rat, error) {
+		return new(big.Rat).SetInt64(int64(r.stoppingTime)), nil
+	},
+	"peak": func(r *sequenceProgress) (*big.Rat, error) {
+		return new(big.Rat).SetInt(r.maxStoneInt), nil
+	},
+	"up": func(r *sequenceProgress) (*big.Rat, error) {
+		return new(big.Rat).SetInt64(int64(r.upMoves)), nil
+	},
+	"down": func(r *sequenceProgress) (*big.Rat, error) {
+		return new(big.Rat).SetInt64(int64(r.downMoves)), nil
+	},
+	"ratio": func(r *sequenceProgress) (*big.Rat, error) {
+		if r.downMoves == 0 {
+			return nil, errors.New("there are no down moves")
+		}
+		return big.NewRat(int64(r.upMoves), int64(r.downMoves)), nil
+	},
+}
+
+// predicateParser compiles predicates over the variables of a result. A value
+// matches when the predicate holds, and not when a term of it cannot be
+// worked out, as when it divides by zero.
+//
+//	predicate  = conjunct { "or" conjunct }
+//	conjunct   = comparison { "and" comparison }
+//	comparison = sum ("==" | "!=" | "<" | "<=" | ">" | ">=") sum
+//	sum        = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/" | "%") unary }
+//	unary      = "-" unary | power
+//	power      = primary [ "^" unary ]
+//	primary    = number | variable | "(" sum ")"
+type predicateParser struct {
+	input []rune
+	pos   int
+}
+
+// parsePredicate compiles s, for example steps == 500, peak > n^2 or ratio > 0.6 and n % 3 == 2
+func parsePredicate(s string) (*searchPredicate, error) {
+	p := &predicateParser{input: []rune(s)}
+	test, err := p.predicate()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+	return &searchPredicate{source: s, test: test}, nil
+}
+
+// matches reports whether the result of a value satisfies the predicate
+func (sp *searchPredicate) matches(r *sequenceProgress) bool {
+	return sp.test(r)
+}
+
+func (p *predicateParser) errorf(format string, args ...interface{}) error {
+	return &exprError{pos: p.pos + 1, msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *predicateParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// acceptWord consumes the next symbol or word if it is one of want
+func (p *predicateParser) acceptWord(want ...string) (string, bool) {
+	p.skipSpaces()
+	rest := string(p.input[p.pos:])
+	for _, w := range want {
+		if !strings.HasPrefix(rest, w) {
+			continue
+		}
+		// A keyword must not run into the name that follows it
+		end := p.pos + len([]rune(w))
+		if unicode.IsLetter([]rune(w)[0]) && end < len(p.input) && (unicode.IsLetter(p.input[end]) || unicode.IsDigit(p.input[end])) {
+			continue
+		}
+		p.pos = end
+		return w, true
+	}
+	return "", false
+}
+
+func (p *predicateParser) predicate() (func(r *sequenceProgress) bool, error) {
+	test, err := p.conjunct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptWord("or", "||"); !ok {
+			return test, nil
+		}
+		rhs, err := p.conjunct()
+		if err != nil {
+			return nil, err
+		}
+		lhs := test
+		test = func(r *sequenceProgress) bool {
+			return lhs(r) || rhs(r)
+		}
+	}
+}
+
+func (p *predicateParser) conjunct() (func(r *sequenceProgress) bool, error) {
+	test, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptWord("and", "&&"); !ok {
+			return test, nil
+		}
+		rhs, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		lhs := test
+		test = func(r *sequenceProgress) bool {
+			return lhs(r) && rhs(r)
+		}
+	}
+}
+
+// The comparisons, the longer first so <= is not read as <. ≤, ≥ and ≠ may also be typed.
+var predicateComparisons = []struct {
+	op    string
+	holds func(c int) bool
+}{
+	{"==", func(c int) bool { return c == 0 }},
+	{"!=", func(c int) bool { return c != 0 }},
+	{"≠", func(c int) bool { return c != 0 }},
+	{"<=", func(c int) bool { return c <= 0 }},
+	{"≤", func(c int) bool { return c <= 0 }},
+	{">=", func(c int) bool { return c >= 0 }},
+	{"≥", func(c int) bool { return c >= 0 }},
+	{"<", func(c int) bool { return c < 0 }},
+	{">", func(c int) bool { return c > 0 }},
+	{"=", func(c int) bool { return c == 0 }},
+}
+
+func (p *predicateParser) comparison() (func(r *sequenceProgress) bool, error) {
+	lhs, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	var holds func(c int) bool
+	for _, c := range predicateComparisons {
+		if _, ok := p.acceptWord(c.op); ok {
+			holds = c.holds
+			break
+		}
+	}
+	if holds == nil {
+		return nil, p.errorf("expected a comparison such as == or <")
+	}
+	rhs, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	return func(r *sequenceProgress) bool {
+		a, err := lhs(r)
+		if err != nil {
+			return false
+		}
+		b, err := rhs(r)
+		if err != nil {
+			return false
+		}
+		return holds(a.Cmp(b))
+	}, nil
+}
+
+func (p *predicateParser) sum() (predicateTerm, error) {
+	v, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		// The minus sign may also be typed as U+2212
+		op, ok := p.acceptWord("+", "-", "−")
+		if !ok {
+			return v, nil
+		}
+		rhs, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		v = binaryTerm(v, rhs, func(a *big.Rat, b *big.Rat) (*big.Rat, error) {
+			if op == "+" {
+				return a.Add(a, b), nil
+			}
+			return a.Sub(a, b), nil
+		})
+	}
+}
+
+func (p *predicateParser) term() (predicateTerm, error) {
+	v, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptWord("*", "×", "/", "%")
+		if !ok {
+			return v, nil
+		}
+		rhs, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		v = binaryTerm(v, rhs, func(a *big.Rat, b *big.Rat) (*big.Rat, error) {
+			switch op {
+			case "/":
+				if b.Sign() == 0 {
+					return nil, errors.New("division by zero")
+				}
+				return a.Quo(a, b), nil
+			case "%":
+				if !a.IsInt() || !b.IsInt() || b.Sign() == 0 {
+					return nil, errors.New("% takes whole numbers and a divisor other than zero")
+				}
+				return a.SetInt(new(big.Int).Mod(a.Num(), b.Num())), nil
+			}
+			if a.Num().BitLen()+b.Num().BitLen() > maxExpressionBits {
+				return nil, errors.New("the product is too large")
+			}
+			return a.Mul(a, b), nil
+		})
+	}
+}
+
+// binaryTerm combines two terms, failing when either does
+func binaryTerm(lhs predicateTerm, rhs predicateTerm, op func(a *big.Rat, b *big.Rat) (*big.Rat, error)) predicateTerm {
+	return func(r *sequenceProgress) (*big.Rat, error) {
+		a, err := lhs(r)
+		if err != nil {
+			return nil, err
+		}
+		b, err := rhs(r)
+		if err != nil {
+			return nil, err
+		}
+		return op(a, b)
+	}
+}
+
+func (p *predicateParser) unary() (predicateTerm, error) {
+	if _, ok := p.acceptWord("-", "−"); ok {
+		v, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(r *sequenceProgress) (*big.Rat, error) {
+			a, err := v(r)
+			if err != nil {
+				return nil, err
+			}
+			return a.Neg(a), nil
+		}, nil
+	}
+	return p.power()
+}
+
+func (p *predicateParser) power() (predicateTerm, error) {
+	v, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.acceptWord("^"); !ok {
+		return v, nil
+	}
+	exp, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return binaryTerm(v, exp, func(a *big.Rat, b *big.Rat) (*big.Rat, error) {
+		if !b.IsInt() || b.Sign() < 0 || !b.Num().IsInt64() {
+			return nil, errors.New("the exponent must be a whole number of at least 0")
+		}
+		k := b.Num().Int64()
+		bits := int64(max(a.Num().BitLen(), a.Denom().BitLen()))
+		if k > maxExpressionBits || (bits > 1 && (bits-1)*k > maxExpressionBits) {
+			return nil, errors.New("the power is too large")
+		}
+		num := new(big.Int).Exp(a.Num(), b.Num(), nil)
+		den := new(big.Int).Exp(a.Denom(), b.Num(), nil)
+		return a.SetFrac(num, den), nil
+	}), nil
+}
+
+func (p *predicateParser) primary() (predicateTerm, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return nil, p.errorf("expected a value")
+	}
+
+	if _, ok := p.acceptWord("("); ok {
+		v, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.acceptWord(")"); !ok {
+			return nil, p.errorf("expected )")
+		}
+		return v, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] < unicode.MaxASCII && (unicode.IsLetter(p.input[p.pos]) || unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	word := string(p.input[start:p.pos])
+	if word == "" {
+		return nil, p.errorf("expected a value but found %q", p.input[p.pos])
+	}
+
+	if v, ok := predicateVariables[word]; ok {
+		return v, nil
+	}
+	c, ok := new(big.Rat).SetString(word)
+	if !ok || strings.ContainsAny(word, "eE/") {
+		p.pos = start
+		return nil, p.errorf("%s is not a number or one of n, steps, stopping, peak, up, down and ratio", word)
+	}
+	// The constant is copied, as the terms work on the values they are given
+	return func(*sequenceProgress) (*big.Rat, error) {
+		return new(big.Rat).Set(c), nil
+	}, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestParsePredicate(t *testing.T) {
+	// 27 takes 111 steps, 41 up and 70 down, falls below itself at step 96 and peaks at 9232
+	r27 := CollatzPerf(*big.NewInt(27), standardMap)
+	one := CollatzPerf(*big.NewInt(1), standardMap)
+	for _, c := range []struct {
+		predicate string
+		report    sequenceProgress
+		want      bool
+	}{
+		{"steps == 111", r27, true},
+		{"steps = 111", r27, true},
+		{"steps != 111", r27, false},
+		{"stopping >= 96 and stopping ≤ 96", r27, true},
+		{"peak > n^2", r27, true},
+		{"peak > n^3", r27, false},
+		{"up + down == steps", r27, true},
+		{"ratio > 0.58 && ratio < 0.59", r27, true},
+		{"up/down == 41/70", r27, true},
+		{"n % 4 == 3", r27, true},
+		{"n % 4 == 1 or steps > 100", r27, true},
+		{"n % 4 == 1 || steps > 200", r27, false},
+		{"-n + 30 == 3", r27, true},
+		{"n − 2×3 == 21", r27, true},
+		{"(n + 1) % 7 == 0", r27, true},
+		{"peak * 2^-1 == 4616", r27, false}, // the exponent must not be negative, so no match
+		{"steps == 0", one, true},
+		{"ratio > 0", one, false}, // 1 has no down moves
+		{"n / (steps - 111) > 0", r27, false},
+		{"steps == 111 and up / (down - 70) > 0 or n == 27", r27, true},
+	} {
+		p, err := parsePredicate(c.predicate)
+		if err != nil {
+			t.Errorf("parsePredicate(%q) failed: %v", c.predicate, err)
+			continue
+		}
+		report := c.report
+		if got := p.matches(&report); got != c.want {
+			t.Errorf("%q is %v for %s, expected %v", c.predicate, got, report.number, c.want)
+		}
+	}
+}
+
+func TestParsePredicateErrors(t *testing.T) {
+	for _, c := range []struct {
+		predicate string
+		pos       int
+		msg       string
+	}{
+		{"", 1, "expected a value"},
+		{"steps", 6, "expected a comparison"},
+		{"steps == ", 10, "expected a value"},
+		{"steps == 5 and", 15, "expected a value"},
+		{"(steps == 5", 8, "expected )"},
+		{"stones > 5", 1, "stones is not a number or one of n, steps"},
+		{"steps == 1e5", 10, "1e5 is not a number"},
+		{"steps == 5 5", 12, "unexpected"},
+		{"android > 1", 1, "android is not a number"},
+	} {
+		_, err := parsePredicate(c.predicate)
+		e, ok := err.(*exprError)
+		if !ok {
+			t.Errorf("parsePredicate(%q) returned %v, expected an error", c.predicate, err)
+			continue
+		}
+		if e.pos != c.pos || !strings.Contains(e.msg, c.msg) {
+			t.Errorf("parsePredicate(%q) = %q at %d, expected %q at %d", c.predicate, e.msg, e.pos, c.msg, c.pos)
+		}
+	}
+}
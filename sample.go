@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+// The largest random sample, whose values are all kept in memory while it runs
+const maxSampleSize = 1000000
+
+// drawSample draws count distinct values uniformly from lower to upper, both
+// included. The same seed always draws the same values.
+func drawSample(lower *big.Int, upper *big.Int, count int64, seed int64) ([]*big.Int, error) {
+	span := new(big.Int).Sub(upper, lower)
+	span.Add(span, oneBig)
+	if count < 1 || count > maxSampleSize {
+		return nil, fmt.Errorf("the sample size must be between 1 and %d", maxSampleSize)
+	}
+	if span.Cmp(big.NewInt(count)) < 0 {
+		return nil, errors.New("the sample is larger than the range")
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	values := make([]*big.Int, 0, count)
+	seen := make(map[string]bool, count)
+	for int64(len(values)) < count {
+		v := new(big.Int).Rand(rng, span)
+		v.Add(v, lower)
+		if !seen[v.String()] {
+			seen[v.String()] = true
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// newSampleTracker follows the sample drawSample draws. Its checkpoint keeps the
+// limits, size and seed rather than the values, which are drawn again to resume.
+func newSampleTracker(lower *big.Int, upper *big.Int, count int64, seed int64) (*sweepTracker, error) {
+	values, err := drawSample(lower, upper, count, seed)
+	if err != nil {
+		return nil, err
+	}
+	t := newListTracker(values)
+	t.state.Values = nil
+	t.state.Lower = lower.String()
+	t.state.Upper = new(big.Int).Add(upper, oneBig).String()
+	t.state.SampleSize = count
+	t.state.Seed = seed
+	return t, nil
+}
+
+// calcStonesSample runs a random sample of the values from lower to upper
+func calcStonesSample(lower string, upper string, size string, seed string, base string, reportFrequency int, search rangeSearch, win fyne.Window) {
+
+	nl, ok := checkValidation(lower, base, win)
+	if !ok {
+		finishSweep()
+		return
+	}
+	nu, ok := checkValidation(upper, base, win)
+	if !ok {
+		finishSweep()
+		return
+	}
+	count, ok := checkValidation(size, "Base 10", win)
+	if !ok {
+		finishSweep()
+		return
+	}
+	s := *big.NewInt(1)
+	if seed != "" {
+		if s, ok = checkValidation(seed, "Base 10", win); !ok {
+			finishSweep()
+			return
+		}
+	}
+	if nl.Sign() <= 0 || nu.Cmp(&nl) < 0 {
+		showInformation("Number Format Error", "The lower limit must be at least 1 and no more than the upper limit", win)
+		finishSweep()
+		return
+	}
+	if !count.IsInt64() || !s.IsInt64() {
+		showInformation("Number Format Error", "The sample size and seed must fit in 64 bits", win)
+		finishSweep()
+		return
+	}
+
+	tracker, err := newSampleTracker(&nl, &nu, count.Int64(), s.Int64())
+	if err != nil {
+		showInformation("Sample Error", err.Error(), win)
+		finishSweep()
+		return
+	}
+	tracker.state.Map = currentSettings().Map.String()
+	runSweep(tracker, reportFrequency, search, win)
+}
+
+// runStatistics summarises the steps of a run against the size of its values
+type runStatistics struct {
+	count            int
+	meanLog2         float64
+	meanSteps        float64
+	minSteps         float64
+	maxSteps         float64
+	meanStoppingTime float64
+	stepsPerBit      float64 // the mean of steps / log2 n, for n above 1
+	stepsPerBitSD    float64
+	expected         float64 // the heuristic steps / log2 n
+}
+
+func newRunStatistics(steps []float64, stoppingTimes []float64, magnitudes []float64, m collatzMap) runStatistics {
+	s := runStatistics{count: len(steps), expected: expectedStepsPerBit(m)}
+	if s.count == 0 {
+		return s
+	}
+
+	s.minSteps, s.maxSteps = steps[0], steps[0]
+	var ratios []float64
+	for i := range steps {
+		s.meanLog2 += magnitudes[i]
+		s.meanSteps += steps[i]
+		s.meanStoppingTime += stoppingTimes[i]
+		s.minSteps = math.Min(s.minSteps, steps[i])
+		s.maxSteps = math.Max(s.maxSteps, steps[i])
+		if magnitudes[i] > 0 {
+			ratios = append(ratios, steps[i]/magnitudes[i])
+		}
+	}
+	s.meanLog2 /= float64(s.count)
+	s.meanSteps /= float64(s.count)
+	s.meanStoppingTime /= float64(s.count)
+
+	for _, r := range ratios {
+		s.stepsPerBit += r
+	}
+	if len(ratios) > 0 {
+		s.stepsPerBit /= float64(len(ratios))
+	}
+	for _, r := range ratios {
+		s.stepsPerBitSD += (r - s.stepsPerBit) * (r - s.stepsPerBit)
+	}
+	if len(ratios) > 1 {
+		s.stepsPerBitSD = math.Sqrt(s.stepsPerBitSD / float64(len(ratios)-1))
+	}
+	return s
+}
+
+// String sets out the statistics in Markdown
+func (s runStatistics) String() string {
+	if s.count == 0 {
+		return ""
+	}
+	return fmt.Sprintf("**Values:** %d, with a mean log2 n of %.2f\n\n"+
+		"**Steps:** %.0f to %.0f, %.1f on average\n\n"+
+		"**Steps ÷ log2 n:** %.3f, standard deviation %.3f\n\n"+
+		"**Heuristic:** %.3f · log2 n, or %.2f · ln n, %.1f steps at the mean log2 n\n\n"+
+		"**Stopping Time:** %.1f on average",
+		s.count, s.meanLog2,
+		s.minSteps, s.maxSteps, s.meanSteps,
+		s.stepsPerBit, s.stepsPerBitSD,
+		s.expected, s.expected/math.Ln2, s.expected*s.meanLog2,
+		s.meanStoppingTime)
+}
+
+// magnitudeSeries charts the steps of each value of a run against its log2,
+// with the steps the heuristic expects
+type magnitudeSeries struct {
+	magnitudes []float64
+	steps      []float64
+	expected   float64
+}
+
+// newMagnitudeSeries sorts the results by log2, as they arrive in any order
+func newMagnitudeSeries(steps []float64, magnitudes []float64, expected float64) *magnitudeSeries {
+	order := make([]int, len(magnitudes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return magnitudes[order[a]] < magnitudes[order[b]]
+	})
+
+	s := &magnitudeSeries{magnitudes: make([]float64, len(order)), steps: make([]float64, len(order)), expected: expected}
+	for i, idx := range order {
+		s.magnitudes[i] = magnitudes[idx]
+		s.steps[i] = steps[idx]
+	}
+	return s
+}
+
+func (s *magnitudeSeries) Bounds() (float64, float64) {
+	return s.magnitudes[0], s.magnitudes[len(s.magnitudes)-1]
+}
+
+func (s *magnitudeSeries) Points(from float64, to float64) (xV []float64, yV []float64) {
+	lo := sort.SearchFloat64s(s.magnitudes, from)
+	hi := sort.Search(len(s.magnitudes), func(i int) bool {
+		return s.magnitudes[i] > to
+	})
+	stride := 1
+	if hi-lo > maxScatterPoints {
+		stride = (hi - lo + maxScatterPoints - 1) / maxScatterPoints
+	}
+	for i := lo; i < hi; i += stride {
+		xV = append(xV, s.magnitudes[i])
+		yV = append(yV, s.steps[i])
+	}
+	return xV, yV
+}
+
+func (s *magnitudeSeries) Nearest(x float64) (float64, float64, string) {
+	i := sort.SearchFloat64s(s.magnitudes, x)
+	if i == len(s.magnitudes) || (i > 0 && x-s.magnitudes[i-1] < s.magnitudes[i]-x) {
+		i--
+	}
+	return s.magnitudes[i], s.steps[i], fmt.Sprintf("2^%.3f: %.0f steps, %.0f expected", s.magnitudes[i], s.steps[i], s.expected*s.magnitudes[i])
+}
+
+func (s *magnitudeSeries) Chart(xV []float64, yV []float64) chart.Chart {
+	lo, hi := xV[0], xV[len(xV)-1]
+	return chart.Chart{
+		XAxis: chart.XAxis{
+			Name:      "log2 n",
+			Style:     chart.Shown(),
+			NameStyle: chart.Shown(),
+		},
+		YAxis: chart.YAxis{
+			Name:      "Steps",
+			Style:     chart.Shown(),
+			NameStyle: chart.Shown(),
+			Range:     &chart.ContinuousRange{},
+		},
+		Series: []chart.Series{
+			chart.ContinuousSeries{
+				Style:   chart.Style{StrokeWidth: chart.Disabled, DotWidth: 2},
+				XValues: xV,
+				YValues: yV,
+			},
+			chart.ContinuousSeries{
+				Name:    "Heuristic",
+				Style:   chart.Style{StrokeColor: chart.ColorRed, StrokeWidth: 2},
+				XValues: []float64{lo, hi},
+				YValues: []float64{s.expected * lo, s.expected * hi},
+			},
+		},
+	}
+}
+
+// The Statistics tab of the Range tab
+var statisticsText *widget.RichText
+var magnitudeChart *chartView
+
+// showRunStatistics sets out the statistics of a finished run and charts its steps against log2 n
+func showRunStatistics(steps []float64, stoppingTimes []float64, magnitudes []float64, m collatzMap) {
+	stats := newRunStatistics(steps, stoppingTimes, magnitudes, m)
+	updateUI(func() {
+		statisticsText.ParseMarkdown(stats.String())
+	})
+	if len(steps) == 0 {
+		magnitudeChart.Clear()
+		return
+	}
+	magnitudeChart.SetSeries(newMagnitudeSeries(steps, magnitudes, stats.expected))
+}
+
+func makeStatisticsTab() fyne.CanvasObject {
+	statisticsText = widget.NewRichTextFromMarkdown("")
+	statisticsText.Wrapping = fyne.TextWrapWord
+	magnitudeChart = newChartView(fyne.NewSize(400, 300))
+	return container.NewBorder(statisticsText, nil, nil, nil, magnitudeChart)
+}
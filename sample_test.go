@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestDrawSample(t *testing.T) {
+	lower := new(big.Int).Lsh(oneBig, 200)
+	upper := new(big.Int).Lsh(oneBig, 201)
+
+	first, err := drawSample(lower, upper, 1000, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := drawSample(lower, upper, 1000, 7)
+	other, _ := drawSample(lower, upper, 1000, 8)
+	seen := make(map[string]bool)
+	for i, v := range first {
+		if v.Cmp(lower) < 0 || v.Cmp(upper) > 0 {
+			t.Errorf("%s is outside the range", v)
+		}
+		if seen[v.String()] {
+			t.Errorf("%s is drawn twice", v)
+		}
+		seen[v.String()] = true
+		if v.Cmp(again[i]) != 0 {
+			t.Fatalf("value %d is %s then %s from the same seed", i, v, again[i])
+		}
+	}
+	if first[0].Cmp(other[0]) == 0 {
+		t.Errorf("seeds 7 and 8 both draw %s first", first[0])
+	}
+
+	// A sample as large as the range is the whole range, both limits included
+	whole, err := drawSample(big.NewInt(10), big.NewInt(14), 5, 1)
+	if err != nil || len(whole) != 5 {
+		t.Fatalf("%d values (%v), expected 10 to 14", len(whole), err)
+	}
+	if _, err := drawSample(big.NewInt(10), big.NewInt(14), 6, 1); err == nil {
+		t.Error("a sample larger than the range is drawn")
+	}
+	if _, err := drawSample(big.NewInt(10), big.NewInt(14), 0, 1); err == nil {
+		t.Error("an empty sample is drawn")
+	}
+}
+
+func TestSampleCheckpoint(t *testing.T) {
+	lower := new(big.Int).Lsh(oneBig, 200)
+	upper := new(big.Int).Lsh(oneBig, 201)
+	tracker, err := newSampleTracker(lower, upper, 1000, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The checkpoint keeps how the sample was drawn, not its values
+	cp := tracker.state
+	cp.Completed = 400
+	if cp.Values != nil || cp.SampleSize != 1000 || cp.Seed != 7 || cp.lastValue() != upper.String() {
+		t.Errorf("the checkpoint keeps %d values and a sample of %d with the seed %d to %s", len(cp.Values), cp.SampleSize, cp.Seed, cp.lastValue())
+	}
+	resumed, err := resumeSweepTracker(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.total != 1000 || resumed.value(400).Cmp(tracker.value(400)) != 0 || resumed.value(999).Cmp(tracker.value(999)) != 0 {
+		t.Errorf("the resumed sample of %d values is not the one drawn", resumed.total)
+	}
+	if d := cp.describe(); d != "of 1000 values drawn from "+lower.String()+" to "+upper.String()+" with the seed 7" {
+		t.Errorf("the checkpoint is described as %q", d)
+	}
+}
+
+func TestRunStatistics(t *testing.T) {
+	// The standard map takes 3/(2 - log2 3) steps a bit, about 10.43 a natural log
+	if e := expectedStepsPerBit(standardMap); math.Abs(e-7.2283) > 1e-4 || math.Abs(e/math.Ln2-10.428) > 1e-3 {
+		t.Errorf("the standard map is expected to take %v steps a bit", e)
+	}
+	if e := expectedStepsPerBit(shortcutMap); math.Abs(e-4.8188) > 1e-4 {
+		t.Errorf("the shortcut map is expected to take %v steps a bit", e)
+	}
+
+	// 1 has no bits, so only 4 and 8 count towards steps a bit
+	s := newRunStatistics([]float64{0, 2, 3}, []float64{0, 1, 1}, []float64{0, 2, 3}, standardMap)
+	if s.count != 3 || s.meanSteps != 5.0/3 || s.minSteps != 0 || s.maxSteps != 3 || s.stepsPerBit != 1 || s.stepsPerBitSD != 0 || s.meanLog2 != 5.0/3 {
+		t.Errorf("the statistics are %+v", s)
+	}
+	if newRunStatistics(nil, nil, nil, standardMap).String() != "" {
+		t.Error("an empty run has statistics")
+	}
+}
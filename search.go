@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// The most matches a search keeps when it is not told to stop sooner, and how
+// often the Matches table is refreshed while they come in
+const maxSearchMatches = 100000
+const matchRefreshInterval = 200 * time.Millisecond
+
+const noSearchText = "Enter a search to list the values that match it"
+
+// rangeSearch is the predicate a Range tab run is searching for, if any, and
+// the number of matches it stops after, 0 for the most it keeps
+type rangeSearch struct {
+	predicate *searchPredicate
+	limit     int
+}
+
+// parseRangeSearch reads the search entries of the Range tab. An empty predicate is no search.
+func parseRangeSearch(predicate string, stopAfter string, win fyne.Window) (rangeSearch, bool) {
+	if predicate == "" {
+		return rangeSearch{}, true
+	}
+	p, err := parsePredicate(predicate)
+	if err != nil {
+		showInformation("Search Error", fmt.Sprintf("The search could not be read: %v", err), win)
+		return rangeSearch{}, false
+	}
+	search := rangeSearch{predicate: p}
+	if stopAfter != "" {
+		k, ok := checkValidation(stopAfter, "Base 10", win)
+		if !ok {
+			return rangeSearch{}, false
+		}
+		if k.Sign() <= 0 || k.Cmp(big.NewInt(maxSearchMatches)) > 0 {
+			showInformation("Search Error", fmt.Sprintf("The search can stop after between 1 and %d matches", maxSearchMatches), win)
+			return rangeSearch{}, false
+		}
+		search.limit = int(k.Int64())
+	}
+	return search, true
+}
+
+// checkpointSearch rebuilds the search of a checkpoint, which is no search if it has no predicate
+func checkpointSearch(cp sweepCheckpoint) (rangeSearch, error) {
+	if cp.Search == "" {
+		return rangeSearch{}, nil
+	}
+	p, err := parsePredicate(cp.Search)
+	if err != nil {
+		return rangeSearch{}, fmt.Errorf("the checkpoint search could not be read: %v", err)
+	}
+	if cp.SearchLimit < 0 || cp.SearchLimit > maxSearchMatches {
+		return rangeSearch{}, fmt.Errorf("the checkpoint search stops after %d matches", cp.SearchLimit)
+	}
+	return rangeSearch{predicate: p, limit: cp.SearchLimit}, nil
+}
+
+// searchSession sets a session searching, with the Matches table following its
+// matches. The search is saved with the session's checkpoints, and a resumed
+// search starts from the matches its checkpoint has, which are computed again.
+func searchSession(session *RunSession, search rangeSearch) {
+	if search.predicate == nil {
+		return
+	}
+	session.predicate = search.predicate
+	session.matchLimit = search.limit
+	if session.matchLimit == 0 {
+		session.matchLimit = maxSearchMatches
+	}
+	session.tracker.state.Search = search.predicate.source
+	session.tracker.state.SearchLimit = search.limit
+	for _, m := range session.tracker.state.Matches {
+		if n, ok := new(big.Int).SetString(m, 10); ok {
+			report := CollatzPerf(*n, session.mapping)
+			report.matched = true
+			session.matches = append(session.matches, report)
+		}
+	}
+	if len(session.matches) >= session.matchLimit {
+		session.Stop()
+	}
+
+	// Called on the collector alone, so the time needs no lock
+	var shown time.Time
+	session.OnMatch = func(sequenceProgress) {
+		if time.Since(shown) >= matchRefreshInterval {
+			shown = time.Now()
+			showMatches(session.Matches(), search)
+		}
+	}
+}
+
+// The Matches tab of the Range tab
+var searchMatches tableRows[sequenceProgress]
+var matchesTable *widget.Table
+var matchesLabel *widget.Label
+
+// showMatches fills the Matches table with the matches of a search
+func showMatches(matches []sequenceProgress, search rangeSearch) {
+	text := noSearchText
+	if search.predicate != nil {
+		text = fmt.Sprintf("%d matches of %s", len(matches), search.predicate.source)
+		if search.limit > 0 && len(matches) >= search.limit {
+			text = fmt.Sprintf("The first %d matches of %s", len(matches), search.predicate.source)
+		} else if len(matches) >= maxSearchMatches {
+			text = fmt.Sprintf("The first %d matches of %s, the most kept", len(matches), search.predicate.source)
+		}
+	}
+	searchMatches.Set(matches)
+	updateUI(func() {
+		matchesLabel.SetText(text)
+		matchesTable.Refresh()
+	})
+}
+
+func makeMatchesTab() fyne.CanvasObject {
+	headings := []string{"Number", "Steps", "Stopping Time", "Max Stone", "Up", "Down"}
+	matchesTable = widget.NewTable(
+		func() (int, int) {
+			return searchMatches.Len() + 1, len(headings)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("Template Wide Label")
+		},
+		func(i widget.TableCellID, cell fyne.CanvasObject) {
+			label := cell.(*widget.Label)
+			if i.Row == 0 {
+				label.SetText(headings[i.Col])
+				return
+			}
+			m, ok := searchMatches.Row(i.Row - 1)
+			if !ok {
+				label.SetText("")
+				return
+			}
+			switch i.Col {
+			case 0:
+				label.SetText(abbreviate(formatValue(m.number)))
+			case 1:
+				label.SetText(fmt.Sprintf("%d", m.steps))
+			case 2:
+				label.SetText(fmt.Sprintf("%d", m.stoppingTime))
+			case 3:
+				label.SetText(abbreviate(formatValue(m.maxStoneInt)))
+			case 4:
+				label.SetText(fmt.Sprintf("%d", m.upMoves))
+			case 5:
+				label.SetText(fmt.Sprintf("%d", m.downMoves))
+			}
+		})
+	matchesTable.StickyRowCount = 1
+	for col, width := range []float32{200, 80, 120, 300, 60, 60} {
+		matchesTable.SetColumnWidth(col, width)
+	}
+	matchesLabel = widget.NewLabel(noSearchText)
+
+	return container.NewBorder(matchesLabel, nil, nil, nil, matchesTable)
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiServer serves Collatz results as JSON. Range jobs run on the same worker
+// pool as the Range tab.
+type apiServer struct {
+	sync.Mutex
+	jobs   map[string]*rangeJob
+	nextID int
+}
+
+// jobRetention is how long a job that has ended can still be looked up
+var jobRetention = time.Hour
+
+// maxTrajectoryStones is the most stones /single returns at a time; the rest
+// are fetched a page at a time with offset
+const maxTrajectoryStones = 10000
+
+type singleResponse struct {
+	Number       string   `json:"number"`
+	Base         int      `json:"base"`
+	Map          string   `json:"map"`
+	Steps        int      `json:"steps"`
+	StoppingTime int      `json:"stoppingTime"`
+	UpMoves      int      `json:"upMoves"`
+	DownMoves    int      `json:"downMoves"`
+	MaxStone     string   `json:"maxStone"`
+	ParityHash   string   `json:"parityHash"`
+	Trajectory   []string `json:"trajectory"`
+	Offset       int      `json:"offset"`
+	Length       int      `json:"length"`
+}
+
+type rangeRequest struct {
+	Lower           string `json:"lower"`
+	Upper           string `json:"upper"`
+	Base            int    `json:"base"`
+	Map             string `json:"map"`
+	ReportFrequency int    `json:"reportFrequency"`
+}
+
+type jobStatus struct {
+	ID                   string        `json:"id"`
+	Status               string        `json:"status"`
+	Map                  string        `json:"map"`
+	Lower                string        `json:"lower"`
+	Upper                string        `json:"upper"`
+	Processed            int64         `json:"processed"`
+	Total                int64         `json:"total"`
+	Percent              float64       `json:"percent"`
+	ValuesPerSecond      float64       `json:"valuesPerSecond"`
+	HighwaterSteps       int           `json:"highwaterSteps"`
+	HighwaterStepsNumber string        `json:"highwaterStepsNumber"`
+	HighwaterStone       string        `json:"highwaterStone"`
+	HighwaterStoneNumber string        `json:"highwaterStoneNumber"`
+	StepRecords          []sweepRecord `json:"stepRecords"`
+	StoneRecords         []sweepRecord `json:"stoneRecords"`
+	StoreFailures        int64         `json:"storeFailures,omitempty"`
+}
+
+// rangeJob is a range sweep started through the API, run by its own session
+type rangeJob struct {
+	sync.Mutex
+	id               string
+	mapping          collatzMap
+	session          *RunSession
+	reportFrequency  int
+	events           *eventBroadcaster
+	lastEvent        time.Time
+	lastProcessed    int64
+	stepRecordsSent  int
+	stoneRecordsSent int
+}
+
+func newAPIServer() *apiServer {
+	return &apiServer{jobs: make(map[string]*rangeJob)}
+}
+
+func (s *apiServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/single/", s.handleSingle)
+	mux.HandleFunc("/range", s.handleRange)
+	mux.HandleFunc("/jobs/", s.handleJob)
+	mux.HandleFunc("/metrics", metricsHandler(s.jobStatuses))
+	return mux
+}
+
+func (s *apiServer) jobStatuses() []jobStatus {
+	s.Lock()
+	jobs := make([]*rangeJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.Unlock()
+
+	statuses := make([]jobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		statuses = append(statuses, job.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		a, _ := strconv.Atoi(statuses[i].ID)
+		b, _ := strconv.Atoi(statuses[j].ID)
+		return a < b
+	})
+	return statuses
+}
+
+// handleSingle serves GET /single/{n}?base=16&map=shortcut&offset=0&limit=100.
+// The trajectory holds at most limit stones from offset, and length is the
+// number of stones in the whole trajectory.
+func (s *apiServer) handleSingle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	base, err := parseBase(r.URL.Query().Get("base"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	mapping, err := parseCollatzMap(r.URL.Query().Get("map"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	n, err := parseStartValue(strings.TrimPrefix(r.URL.Path, "/single/"), base)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	offset, err := parseQueryCount(r.URL.Query().Get("offset"), "offset", 0, 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parseQueryCount(r.URL.Query().Get("limit"), "limit", 1, maxTrajectoryStones)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rep := Collatz(*new(big.Int).Set(n), mapping, nil, 100)
+
+	length := rep.trajectory.Len()
+	from := min(offset, length)
+	to := min(from+limit, length)
+	trajectory := make([]string, 0, to-from)
+	rep.trajectory.Walk(from, to, func(i int, stone *big.Int) {
+		trajectory = append(trajectory, stone.Text(base))
+	})
+
+	writeJSON(w, http.StatusOK, singleResponse{
+		Number:       n.Text(base),
+		Base:         base,
+		Map:          mapping.String(),
+		Steps:        rep.steps,
+		StoppingTime: rep.stoppingTime,
+		UpMoves:      rep.upMoves,
+		DownMoves:    rep.downMoves,
+		MaxStone:     rep.maxStoneInt.Text(base),
+		ParityHash:   strconv.FormatUint(rep.parityHash, 16),
+		Trajectory:   trajectory,
+		Offset:       from,
+		Length:       length,
+	})
+}
+
+// handleRange serves POST /range, which starts a sweep from lower to upper, both included
+func (s *apiServer) handleRange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	var req rangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	if req.Base == 0 {
+		req.Base = 10
+	}
+	base, err := parseBase(strconv.Itoa(req.Base))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	mapping, err := parseCollatzMap(req.Map)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	lower, err := parseStartValue(req.Lower, base)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("lower: %v", err))
+		return
+	}
+	upper, err := parseStartValue(req.Upper, base)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("upper: %v", err))
+		return
+	}
+	if upper.Cmp(lower) == -1 {
+		writeError(w, http.StatusBadRequest, "Upper limit is smaller than the lower limit")
+		return
+	}
+	tracker, err := newSweepTracker(lower, new(big.Int).Add(upper, oneBig))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.ReportFrequency <= 0 {
+		req.ReportFrequency = reportFreqencyInterval
+	}
+
+	job := s.startJob(tracker, mapping, req.ReportFrequency)
+	writeJSON(w, http.StatusAccepted, map[string]string{"id": job.id})
+}
+
+// handleJob serves GET /jobs/{id} for progress, DELETE /jobs/{id} to cancel
+// and GET /jobs/{id}/events to stream progress as Server-Sent Events
+func (s *apiServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	id, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/jobs/"), "/")
+
+	s.Lock()
+	job, ok := s.jobs[id]
+	s.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no job %q", id))
+		return
+	}
+
+	if sub == "events" && r.Method == http.MethodGet {
+		streamJobEvents(w, r, job)
+		return
+	}
+	if sub != "" {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no such resource %q", r.URL.Path))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, job.Status())
+	case http.MethodDelete:
+		job.Cancel()
+		writeJSON(w, http.StatusOK, job.Status())
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "use GET or DELETE")
+	}
+}
+
+// streamJobEvents sends the current state of the job and then every progress
+// event until the job ends or the client goes away
+func streamJobEvents(w http.ResponseWriter, r *http.Request, job *rangeJob) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	events := job.events.Subscribe()
+	defer job.events.Unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	current := job.Event()
+	if current.Status != sessionRunning {
+		writeServerSentEvent(w, "done", current)
+		flusher.Flush()
+		return
+	}
+	writeServerSentEvent(w, "progress", current)
+	flusher.Flush()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			// An event published as the stream began may be older than the
+			// current state, which already holds its records
+			if e.Status == sessionRunning && e.Processed <= current.Processed {
+				continue
+			}
+			name := "progress"
+			if e.Status != sessionRunning {
+				name = "done"
+			}
+			if writeServerSentEvent(w, name, e) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *apiServer) startJob(tracker *sweepTracker, mapping collatzMap, reportFrequency int) *rangeJob {
+	s.Lock()
+	s.nextID++
+	job := &rangeJob{
+		id:              strconv.Itoa(s.nextID),
+		mapping:         mapping,
+		session:         newRunSession(tracker, mapping, results),
+		reportFrequency: reportFrequency,
+		events:          newEventBroadcaster(),
+		lastEvent:       time.Now(),
+	}
+	s.jobs[job.id] = job
+	s.Unlock()
+
+	job.session.OnReport = func(report sequenceProgress) {
+		if job.session.Snapshot().Processed%int64(job.reportFrequency) == 0 {
+			job.publish()
+		}
+	}
+
+	go func() {
+		job.session.Run()
+		job.publish()
+		job.events.Close()
+		time.AfterFunc(jobRetention, func() {
+			s.Lock()
+			delete(s.jobs, job.id)
+			s.Unlock()
+		})
+	}()
+	return job
+}
+
+// publish sends the progress made since the previous event to the job's subscribers
+func (j *rangeJob) publish() {
+	j.Lock()
+	defer j.Unlock()
+
+	now := time.Now()
+	snap := j.session.Snapshot()
+	state := j.session.State()
+
+	e := progressEvent{
+		Job:                  j.id,
+		Status:               snap.Status,
+		Processed:            snap.Processed,
+		Total:                snap.Total,
+		Percent:              percentOf(snap.Processed, snap.Total),
+		HighwaterSteps:       state.HighwaterSteps,
+		HighwaterStepsNumber: state.HighwaterStepsNumber,
+		HighwaterStone:       state.HighwaterStone,
+		HighwaterStoneNumber: state.HighwaterStoneNumber,
+		NewStepRecords:       state.StepRecords[j.stepRecordsSent:],
+		NewStoneRecords:      state.StoneRecords[j.stoneRecordsSent:],
+	}
+	if elapsed := now.Sub(j.lastEvent).Seconds(); elapsed > 0 {
+		e.ValuesPerSecond = float64(snap.Processed-j.lastProcessed) / elapsed
+	}
+
+	j.lastEvent = now
+	j.lastProcessed = snap.Processed
+	j.stepRecordsSent = len(state.StepRecords)
+	j.stoneRecordsSent = len(state.StoneRecords)
+	j.events.Publish(e)
+}
+
+// Event returns the current state of the job without consuming any records
+func (j *rangeJob) Event() progressEvent {
+	st := j.Status()
+	return progressEvent{
+		Job:                  st.ID,
+		Status:               st.Status,
+		Processed:            st.Processed,
+		Total:                st.Total,
+		Percent:              st.Percent,
+		ValuesPerSecond:      st.ValuesPerSecond,
+		HighwaterSteps:       st.HighwaterSteps,
+		HighwaterStepsNumber: st.HighwaterStepsNumber,
+		HighwaterStone:       st.HighwaterStone,
+		HighwaterStoneNumber: st.HighwaterStoneNumber,
+		NewStepRecords:       st.StepRecords,
+		NewStoneRecords:      st.StoneRecords,
+	}
+}
+
+func (j *rangeJob) Cancel() {
+	j.session.Stop()
+}
+
+func (j *rangeJob) Status() jobStatus {
+	snap := j.session.Snapshot()
+	state := j.session.State()
+
+	st := jobStatus{
+		ID:                   j.id,
+		Status:               snap.Status,
+		Map:                  j.mapping.String(),
+		Lower:                state.Lower,
+		Upper:                state.lastValue(),
+		Processed:            snap.Processed,
+		Total:                snap.Total,
+		Percent:              percentOf(snap.Processed, snap.Total),
+		HighwaterSteps:       state.HighwaterSteps,
+		HighwaterStepsNumber: state.HighwaterStepsNumber,
+		HighwaterStone:       state.HighwaterStone,
+		HighwaterStoneNumber: state.HighwaterStoneNumber,
+		StepRecords:          state.StepRecords,
+		StoneRecords:         state.StoneRecords,
+		StoreFailures:        snap.StoreFailures,
+	}
+	if seconds := snap.Elapsed.Seconds(); seconds > 0 {
+		st.ValuesPerSecond = float64(snap.Processed) / seconds
+	}
+	return st
+}
+
+func percentOf(done int64, total int64) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(done) / float64(total) * 100
+}
+
+func parseBase(s string) (int, error) {
+	if s == "" {
+		return 10, nil
+	}
+	base, err := strconv.Atoi(s)
+	if err != nil || base < 2 || base > 62 {
+		return 0, fmt.Errorf("base %q must be a number from 2 to 62", s)
+	}
+	return base, nil
+}
+
+// parseQueryCount parses a count given in a query, from least up to most, or
+// of any size from least when most is 0. An empty count is the default, most.
+func parseQueryCount(s string, name string, least int, most int) (int, error) {
+	if s == "" {
+		return most, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < least || (most > 0 && n > most) {
+		if most > 0 {
+			return 0, fmt.Errorf("%s %q must be a number from %d to %d", name, s, least, most)
+		}
+		return 0, fmt.Errorf("%s %q must be a number of at least %d", name, s, least)
+	}
+	return n, nil
+}
+
+// parseStartValue parses a positive starting value in the given base
+func parseStartValue(s string, base int) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return nil, fmt.Errorf("the entry %s is not a valid input for Base %d", s, base)
+	}
+	if n.Sign() < 1 {
+		return nil, errors.New("starting values must be greater than zero")
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
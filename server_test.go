@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestServer serves the API on a local port for the length of a test
+func startTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	startTestPool()
+	server := httptest.NewServer(newAPIServer().Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+// request makes a request of the test server, decoding the JSON response into v
+func request(t *testing.T, method string, url string, body string, v interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("%s %s returned invalid JSON: %v", method, url, err)
+	}
+	return resp.StatusCode
+}
+
+// startRangeJob posts a range and returns the id of its job
+func startRangeJob(t *testing.T, server *httptest.Server, body string) string {
+	t.Helper()
+	var started map[string]string
+	if code := request(t, http.MethodPost, server.URL+"/range", body, &started); code != http.StatusAccepted || started["id"] == "" {
+		t.Fatalf("POST /range returned %d and %v", code, started)
+	}
+	return started["id"]
+}
+
+func TestServerSingle(t *testing.T) {
+	server := startTestServer(t)
+
+	var got singleResponse
+	if code := request(t, http.MethodGet, server.URL+"/single/1b?base=16&map=shortcut", "", &got); code != http.StatusOK {
+		t.Fatalf("GET /single/1b returned %d", code)
+	}
+	want := CollatzPerf(*big.NewInt(27), shortcutMap)
+	if got.Number != "1b" || got.Base != 16 || got.Map != "shortcut" {
+		t.Errorf("the response is for %s in base %d with the %s map, expected 1b in base 16 with the shortcut map", got.Number, got.Base, got.Map)
+	}
+	if got.Steps != want.steps || got.StoppingTime != want.stoppingTime || got.UpMoves != want.upMoves || got.DownMoves != want.downMoves {
+		t.Errorf("the response has %d steps, %d to stop, %d up and %d down, expected %d, %d, %d and %d",
+			got.Steps, got.StoppingTime, got.UpMoves, got.DownMoves, want.steps, want.stoppingTime, want.upMoves, want.downMoves)
+	}
+	if got.MaxStone != "1208" {
+		t.Errorf("the largest stone is %s, expected 1208, the stone 4616 that 27 reaches", got.MaxStone)
+	}
+	if len(got.Trajectory) != want.steps+1 || got.Trajectory[0] != "1b" || got.Trajectory[len(got.Trajectory)-1] != "1" {
+		t.Errorf("the trajectory is %v, expected %d stones from 1b to 1", got.Trajectory, want.steps+1)
+	}
+}
+
+func TestServerSinglePages(t *testing.T) {
+	server := startTestServer(t)
+
+	// 27 reaches 1 after 111 steps, so its trajectory is 112 stones
+	var got singleResponse
+	if code := request(t, http.MethodGet, server.URL+"/single/27?offset=100&limit=5", "", &got); code != http.StatusOK {
+		t.Fatalf("GET /single/27 returned %d", code)
+	}
+	want := Collatz(*big.NewInt(27), standardMap, nil, 100).trajectory.Stones(100, 5)
+	if got.Offset != 100 || got.Length != 112 || len(got.Trajectory) != 5 || got.Trajectory[0] != want[0].String() || got.Trajectory[4] != want[4].String() {
+		t.Errorf("the page is %v from %d of %d stones, expected %v from 100 of 112", got.Trajectory, got.Offset, got.Length, want)
+	}
+
+	// A page that runs past the end is cut short
+	if request(t, http.MethodGet, server.URL+"/single/27?offset=110", "", &got); got.Offset != 110 || len(got.Trajectory) != 2 || got.Trajectory[1] != "1" {
+		t.Errorf("the last page is %v from %d, expected the 2 stones to 1 from 110", got.Trajectory, got.Offset)
+	}
+	if request(t, http.MethodGet, server.URL+"/single/27?offset=500", "", &got); got.Offset != 112 || len(got.Trajectory) != 0 {
+		t.Errorf("the page past the end is %v from %d, expected none from 112", got.Trajectory, got.Offset)
+	}
+}
+
+func TestServerJobExpiry(t *testing.T) {
+	saved := jobRetention
+	t.Cleanup(func() {
+		jobRetention = saved
+	})
+	jobRetention = 50 * time.Millisecond
+
+	server := startTestServer(t)
+	id := startRangeJob(t, server, `{"lower": "1", "upper": "100"}`)
+
+	// A job that has ended is forgotten once the retention has passed
+	waitUntil(t, "the job to expire", func() bool {
+		var got map[string]interface{}
+		return request(t, http.MethodGet, server.URL+"/jobs/"+id, "", &got) == http.StatusNotFound
+	})
+}
+
+func TestServerSingleInvalid(t *testing.T) {
+	server := startTestServer(t)
+
+	for _, path := range []string{"/single/0", "/single/-5", "/single/xyz", "/single/1g?base=16", "/single/27?base=1", "/single/27?map=unknown", "/single/27?offset=-1", "/single/27?limit=0", "/single/27?limit=10001"} {
+		var got map[string]string
+		if code := request(t, http.MethodGet, server.URL+path, "", &got); code != http.StatusBadRequest || got["error"] == "" {
+			t.Errorf("GET %s returned %d and %v, expected 400 and an error", path, code, got)
+		}
+	}
+}
+
+func TestServerRangeJob(t *testing.T) {
+	server := startTestServer(t)
+	id := startRangeJob(t, server, `{"lower": "1", "upper": "2000"}`)
+
+	var status jobStatus
+	waitUntil(t, "the job to finish", func() bool {
+		if code := request(t, http.MethodGet, server.URL+"/jobs/"+id, "", &status); code != http.StatusOK {
+			t.Fatalf("GET /jobs/%s returned %d", id, code)
+		}
+		return status.Status != sessionRunning
+	})
+	if status.Upper != "2000" || status.Status != sessionFinished || status.Processed != 2000 || status.Total != 2000 || status.Percent != 100 {
+		t.Errorf("the job to %s is %s after %d of %d values, expected finished after 2000 to 2000", status.Upper, status.Status, status.Processed, status.Total)
+	}
+	if status.HighwaterSteps != 181 || status.HighwaterStepsNumber != "1161" {
+		t.Errorf("the most steps are %d for %s, expected 181 for 1161", status.HighwaterSteps, status.HighwaterStepsNumber)
+	}
+
+	if code := request(t, http.MethodDelete, server.URL+"/jobs/"+id, "", &status); code != http.StatusOK || status.Status != sessionFinished {
+		t.Errorf("DELETE /jobs/%s returned %d and a job that is %s", id, code, status.Status)
+	}
+}
+
+func TestServerCancelJob(t *testing.T) {
+	server := startTestServer(t)
+	id := startRangeJob(t, server, `{"lower": "1", "upper": "100000000"}`)
+
+	var status jobStatus
+	if code := request(t, http.MethodDelete, server.URL+"/jobs/"+id, "", &status); code != http.StatusOK {
+		t.Fatalf("DELETE /jobs/%s returned %d", id, code)
+	}
+	waitUntil(t, "the job to stop", func() bool {
+		request(t, http.MethodGet, server.URL+"/jobs/"+id, "", &status)
+		return status.Status != sessionRunning
+	})
+	if status.Status != sessionCancelled || status.Processed >= status.Total {
+		t.Errorf("the job is %s after %d of %d values, expected cancelled part way", status.Status, status.Processed, status.Total)
+	}
+}
+
+func TestServerInvalidRange(t *testing.T) {
+	server := startTestServer(t)
+
+	for _, body := range []string{`{"lower": "0", "upper": "10"}`, `{"lower": "10", "upper": "1"}`, `{"lower": "1", "upper": "z"}`, `{"lower": "1", "upper": "10", "map": "unknown"}`, `{"lower": "1", "upper": "100000000000000000000"}`, `not json`} {
+		var got map[string]string
+		if code := request(t, http.MethodPost, server.URL+"/range", body, &got); code != http.StatusBadRequest || got["error"] == "" {
+			t.Errorf("POST /range %s returned %d and %v, expected 400 and an error", body, code, got)
+		}
+	}
+}
+
+func TestServerUnknownJob(t *testing.T) {
+	server := startTestServer(t)
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		var got map[string]string
+		if code := request(t, method, server.URL+"/jobs/999", "", &got); code != http.StatusNotFound || got["error"] == "" {
+			t.Errorf("%s /jobs/999 returned %d and %v, expected 404 and an error", method, code, got)
+		}
+	}
+}
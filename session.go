@@ -0,0 +1,359 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// The states of a run
+const (
+	sessionRunning   = "running"
+	sessionFinished  = "finished"
+	sessionCancelled = "cancelled"
+)
+
+// RunSession owns one range run: the channel its results come back on, the
+// controls that pause and stop it, the results themselves and its lifecycle.
+// Every run, whether from the Range tab, the API or a distributed block, has
+// its own session, so runs can go on at the same time without sharing state.
+type RunSession struct {
+	sync.Mutex
+	mapping collatzMap
+	tracker *sweepTracker
+	store   *resultStore
+	reports chan sequenceProgress
+	work    sync.WaitGroup
+
+	pauseChannel  chan bool
+	stepChannel   chan bool
+	resumeChannel chan bool
+	stopChannel   chan bool
+	stopOnce      sync.Once
+	done          chan bool
+
+	status     string
+	dispatched int64
+	processed  int64
+	started    time.Time
+	finished   time.Time
+
+	// The results that could not be written to the results database, and why the first was not
+	storeFailures int64
+	storeError    error
+
+	// The steps, stopping time and log2 of each value, kept for charting when recordSteps is set
+	recordSteps   bool
+	steps         []float64
+	stoppingTimes []float64
+	numbers       []float64
+	magnitudes    []float64
+
+	// A search keeps the values whose results satisfy its predicate, and stops
+	// handing out values once it has matchLimit of them
+	predicate  *searchPredicate
+	matchLimit int
+	matches    []sequenceProgress
+
+	// OnReport is called from the session's collector after each result is folded in
+	OnReport func(report sequenceProgress)
+	// OnMatch is called from the session's collector for each result of a search that matches
+	OnMatch func(report sequenceProgress)
+}
+
+// sessionSnapshot is a consistent copy of the progress of a session
+type sessionSnapshot struct {
+	Status               string
+	Dispatched           int64
+	Processed            int64
+	Total                int64
+	Elapsed              time.Duration
+	QueueDepth           int
+	HighwaterSteps       int
+	HighwaterStepsNumber string
+	HighwaterStone       string
+	HighwaterStoneNumber string
+	StoreFailures        int64
+	StoreError           error
+}
+
+// newRunSession creates a session for the values the tracker has not yet
+// completed. store may be nil, in which case every value is computed.
+func newRunSession(tracker *sweepTracker, mapping collatzMap, store *resultStore) *RunSession {
+	return &RunSession{
+		mapping:       mapping,
+		tracker:       tracker,
+		store:         store,
+		reports:       make(chan sequenceProgress, 10000),
+		pauseChannel:  make(chan bool, 1),
+		stepChannel:   make(chan bool, 1),
+		resumeChannel: make(chan bool, 1),
+		stopChannel:   make(chan bool),
+		done:          make(chan bool),
+		status:        sessionRunning,
+		dispatched:    tracker.state.Completed,
+		processed:     tracker.state.Completed,
+		started:       time.Now(),
+	}
+}
+
+// Run hands the values to the worker pool and returns once every result is in
+func (s *RunSession) Run() {
+	collected := make(chan bool)
+	go s.collect(collected)
+
+	paused := false
+	stopped := false
+
+	for offset := s.tracker.state.Completed; offset < s.tracker.total; offset++ {
+		if !s.control(&paused) {
+			stopped = true
+			break
+		}
+		n := s.tracker.value(offset)
+
+		s.Lock()
+		s.dispatched++
+		s.Unlock()
+
+		// Values already in the results database are reported without being computed again.
+		// A search is not, as the database does not keep the up and down moves.
+		if s.store != nil && s.mapping == standardMap && s.predicate == nil {
+			if e, ok := s.store.Get(n); ok {
+				if report, err := e.progress(); err == nil {
+					s.reports <- report
+					continue
+				}
+			}
+		}
+
+		s.work.Add(1)
+		workDistributorChannel <- workItem{value: *n, mapping: s.mapping, predicate: s.predicate, results: s.reports, done: &s.work}
+	}
+
+	s.work.Wait()
+	close(s.reports)
+	<-collected
+
+	s.Lock()
+	s.finished = time.Now()
+	if stopped {
+		s.status = sessionCancelled
+	} else {
+		s.status = sessionFinished
+	}
+	s.Unlock()
+	close(s.done)
+}
+
+func (s *RunSession) collect(collected chan bool) {
+	for report := range s.reports {
+		var storeErr error
+		if s.store != nil && s.mapping == standardMap {
+			storeErr = s.store.Put(newStoreEntry(report))
+		}
+
+		// A result for a value that is not in the sweep is dropped
+		s.Lock()
+		if !s.tracker.add(report) {
+			s.Unlock()
+			continue
+		}
+		if storeErr != nil {
+			// Only the first failure is kept, as a run may have millions of them
+			if s.storeFailures == 0 {
+				s.storeError = storeErr
+			}
+			s.storeFailures++
+		}
+		s.processed++
+		if s.recordSteps {
+			s.steps = append(s.steps, float64(report.steps))
+			s.stoppingTimes = append(s.stoppingTimes, float64(report.stoppingTime))
+			sf, _ := bigIntToFloat64(report.number)
+			s.numbers = append(s.numbers, sf)
+			s.magnitudes = append(s.magnitudes, log2Big(report.number))
+		}
+		limited := false
+		if report.matched {
+			s.matches = append(s.matches, report)
+			limited = s.matchLimit > 0 && len(s.matches) >= s.matchLimit
+		}
+		s.Unlock()
+
+		// The values already handed out still complete, and may match before those found
+		if limited {
+			s.Stop()
+		}
+		if s.OnReport != nil {
+			s.OnReport(report)
+		}
+		if report.matched && s.OnMatch != nil {
+			s.OnMatch(report)
+		}
+	}
+	close(collected)
+}
+
+// control applies Pause, Step, Resume and Stop to the work distribution. It
+// blocks while the session is paused and returns false once it has been
+// stopped. Step releases a single value and pauses again.
+func (s *RunSession) control(paused *bool) bool {
+	for {
+		if *paused {
+			select {
+			case <-s.resumeChannel:
+				*paused = false
+				return true
+			case <-s.stepChannel:
+				return true
+			case <-s.stopChannel:
+				return false
+			}
+		}
+		select {
+		case <-s.pauseChannel:
+			*paused = true
+		case <-s.stepChannel:
+			*paused = true
+			return true
+		case <-s.stopChannel:
+			return false
+		default:
+			return true
+		}
+	}
+}
+
+func (s *RunSession) Pause() {
+	signal(s.pauseChannel)
+}
+
+func (s *RunSession) Step() {
+	signal(s.stepChannel)
+}
+
+func (s *RunSession) Resume() {
+	signal(s.resumeChannel)
+}
+
+// Stop ends the distribution of values. Values already handed out still complete.
+func (s *RunSession) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stopChannel)
+	})
+}
+
+// Done is closed once the session has finished or been stopped and every result is in
+func (s *RunSession) Done() chan bool {
+	return s.done
+}
+
+func (s *RunSession) Snapshot() sessionSnapshot {
+	s.Lock()
+	defer s.Unlock()
+
+	end := time.Now()
+	if s.status != sessionRunning {
+		end = s.finished
+	}
+	return sessionSnapshot{
+		Status:               s.status,
+		Dispatched:           s.dispatched,
+		Processed:            s.processed,
+		Total:                s.tracker.total,
+		Elapsed:              end.Sub(s.started),
+		QueueDepth:           len(s.reports),
+		HighwaterSteps:       s.tracker.state.HighwaterSteps,
+		HighwaterStepsNumber: s.tracker.state.HighwaterStepsNumber,
+		HighwaterStone:       s.tracker.state.HighwaterStone,
+		HighwaterStoneNumber: s.tracker.state.HighwaterStoneNumber,
+		StoreFailures:        s.storeFailures,
+		StoreError:           s.storeError,
+	}
+}
+
+// HighwaterStoneBits returns the bit length of the largest stone of the results folded in so far
+func (s *RunSession) HighwaterStoneBits() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.tracker.maxStone.BitLen()
+}
+
+// State returns a copy of the contiguous state that is written to checkpoints
+func (s *RunSession) State() sweepCheckpoint {
+	s.Lock()
+	defer s.Unlock()
+
+	state := s.tracker.state
+	state.StepRecords = append([]sweepRecord(nil), state.StepRecords...)
+	state.StoneRecords = append([]sweepRecord(nil), state.StoneRecords...)
+	state.Matches = append([]string(nil), state.Matches...)
+	state.Histogram = make(map[int]int64, len(s.tracker.state.Histogram))
+	for steps, count := range s.tracker.state.Histogram {
+		state.Histogram[steps] = count
+	}
+	return state
+}
+
+// Steps returns copies of the steps recorded for each value and the values themselves
+func (s *RunSession) Steps() (steps []float64, numbers []float64) {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]float64(nil), s.steps...), append([]float64(nil), s.numbers...)
+}
+
+// StoppingTimes returns a copy of the stopping time recorded for each value, in the order of Steps
+func (s *RunSession) StoppingTimes() []float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]float64(nil), s.stoppingTimes...)
+}
+
+// Magnitudes returns a copy of log2 of each value, in the order of Steps, which
+// unlike the values themselves is exact however large they are
+func (s *RunSession) Magnitudes() []float64 {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]float64(nil), s.magnitudes...)
+}
+
+// Matches returns the matches of a search in the order of its values, up to
+// its limit. As every value before the last of these was handed out before
+// the search stopped, they are the first matches, whatever order the results
+// came back in.
+func (s *RunSession) Matches() []sequenceProgress {
+	s.Lock()
+	defer s.Unlock()
+
+	matches := append([]sequenceProgress(nil), s.matches...)
+	sort.Slice(matches, func(i, j int) bool {
+		a, _ := s.tracker.offset(matches[i].number)
+		b, _ := s.tracker.offset(matches[j].number)
+		return a < b
+	})
+	if s.matchLimit > 0 && len(matches) > s.matchLimit {
+		matches = matches[:s.matchLimit]
+	}
+	return matches
+}
+
+// Checkpoint writes the tracker state if the checkpoint interval has passed or force is set
+func (s *RunSession) Checkpoint(force bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.tracker.checkpoint(force)
+}
+
+// signal sends on a control channel without blocking if a signal is already waiting
+func signal(c chan bool) {
+	select {
+	case c <- true:
+	default:
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// expectedMarks works out the high water marks of [lower, upper) one value at a time
+func expectedMarks(lower int64, upper int64, mapping collatzMap) (steps int, stepsNumber string, stone string, stoneNumber string, histogram map[int]int64) {
+	histogram = make(map[int]int64)
+	maxStone := big.NewInt(0)
+	for i := lower; i < upper; i++ {
+		r := CollatzPerf(*big.NewInt(i), mapping)
+		histogram[r.steps]++
+		if r.steps > steps || stepsNumber == "" {
+			steps, stepsNumber = r.steps, r.number.String()
+		}
+		if r.maxStoneInt.Cmp(maxStone) == 1 {
+			maxStone.Set(r.maxStoneInt)
+			stone, stoneNumber = maxStone.String(), r.number.String()
+		}
+	}
+	return
+}
+
+func TestConcurrentSessions(t *testing.T) {
+	startTestPool()
+
+	runs := []struct {
+		lower, upper int64
+		mapping      collatzMap
+	}{
+		{1, 20001, standardMap},
+		{20001, 40001, standardMap},
+		{1, 20001, shortcutMap},
+	}
+	sessions := make([]*RunSession, len(runs))
+	for i, run := range runs {
+		tracker, err := newSweepTracker(big.NewInt(run.lower), big.NewInt(run.upper))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sessions[i] = newRunSession(tracker, run.mapping, nil)
+		sessions[i].recordSteps = true
+	}
+
+	// The sessions share the worker pool but nothing else
+	var wg sync.WaitGroup
+	for _, session := range sessions {
+		wg.Add(1)
+		go func(session *RunSession) {
+			defer wg.Done()
+			session.Run()
+		}(session)
+	}
+	wg.Wait()
+
+	for i, run := range runs {
+		snap, state := sessions[i].Snapshot(), sessions[i].State()
+		count := run.upper - run.lower
+		if snap.Status != sessionFinished || snap.Processed != count || state.Completed != count {
+			t.Errorf("session %d is %s after %d values with %d completed, expected %d", i, snap.Status, snap.Processed, state.Completed, count)
+		}
+		if steps, _ := sessions[i].Steps(); int64(len(steps)) != count {
+			t.Errorf("session %d recorded the steps of %d values, expected %d", i, len(steps), count)
+		}
+
+		steps, stepsNumber, stone, stoneNumber, histogram := expectedMarks(run.lower, run.upper, run.mapping)
+		if snap.HighwaterSteps != steps || snap.HighwaterStepsNumber != stepsNumber || snap.HighwaterStone != stone || snap.HighwaterStoneNumber != stoneNumber {
+			t.Errorf("session %d has the marks %d for %s and %s for %s, expected %d for %s and %s for %s", i,
+				snap.HighwaterSteps, snap.HighwaterStepsNumber, snap.HighwaterStone, snap.HighwaterStoneNumber, steps, stepsNumber, stone, stoneNumber)
+		}
+		if state.HighwaterSteps != snap.HighwaterSteps || state.HighwaterStoneNumber != snap.HighwaterStoneNumber {
+			t.Errorf("session %d has a snapshot that differs from its checkpoint state", i)
+		}
+		if !reflect.DeepEqual(state.Histogram, histogram) {
+			t.Errorf("session %d has the histogram %v, expected %v", i, state.Histogram, histogram)
+		}
+	}
+}
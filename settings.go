@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"strconv"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+// The preferences the settings, the window and the last values entered are kept in
+const (
+	entryBaseKey       = "entryBase"
+	workersKey         = "workers"
+	mapKey             = "map"
+	chartStyleKey      = "chartStyle"
+	heuristicsKey      = "heuristics"
+	themeKey           = "theme"
+	exportDirKey       = "exportDir"
+	windowWidthKey     = "windowWidth"
+	windowHeightKey    = "windowHeight"
+	lastValueKey       = "lastValue"
+	lastSingleBaseKey  = "lastSingleBase"
+	lastRangeBaseKey   = "lastRangeBase"
+	lastLowerKey       = "lastLower"
+	lastUpperKey       = "lastUpper"
+	lastStrideKey      = "lastStride"
+	lastResidueKey     = "lastResidue"
+	lastSearchKey      = "lastSearch"
+	lastStopAfterKey   = "lastStopAfter"
+	reportFrequencyKey = "reportFrequency"
+)
+
+// The largest worker pool the Settings dialog accepts
+const maxWorkers = 10000
+
+var chartStyles = []string{"Line", "Dots", "Line and dots"}
+var themeNames = []string{"System", "Light", "Dark"}
+
+// appSettings are the defaults chosen in the Settings dialog
+type appSettings struct {
+	EntryBase  string
+	Workers    int
+	Map        collatzMap
+	ChartStyle string
+	Heuristics bool // the heuristics are drawn over the charts
+	Theme      string
+	ExportDir  string
+}
+
+// The settings in use, read by the engine and chart goroutines as well as the UI
+var settingsLock sync.Mutex
+var settings = defaultSettings()
+
+func defaultSettings() appSettings {
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		dir = "."
+	}
+	return appSettings{
+		EntryBase:  "Base 10",
+		Workers:    workerCount,
+		Map:        standardMap,
+		ChartStyle: chartStyles[0],
+		Heuristics: true,
+		Theme:      themeNames[0],
+		ExportDir:  dir,
+	}
+}
+
+func currentSettings() appSettings {
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+
+	return settings
+}
+
+func setSettings(s appSettings) {
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+
+	settings = s
+}
+
+// loadSettings reads the settings from the preferences, using the default for any that are missing or not valid
+func loadSettings(p fyne.Preferences) appSettings {
+	d := defaultSettings()
+	s := appSettings{
+		EntryBase:  oneOf(p.StringWithFallback(entryBaseKey, d.EntryBase), entryBases(true), d.EntryBase),
+		Workers:    p.IntWithFallback(workersKey, d.Workers),
+		Map:        d.Map,
+		ChartStyle: oneOf(p.StringWithFallback(chartStyleKey, d.ChartStyle), chartStyles, d.ChartStyle),
+		Heuristics: p.BoolWithFallback(heuristicsKey, d.Heuristics),
+		Theme:      oneOf(p.StringWithFallback(themeKey, d.Theme), themeNames, d.Theme),
+		ExportDir:  p.StringWithFallback(exportDirKey, d.ExportDir),
+	}
+	if m, err := parseCollatzMap(p.String(mapKey)); err == nil {
+		s.Map = m
+	}
+	if s.Workers < 1 || s.Workers > maxWorkers {
+		s.Workers = d.Workers
+	}
+	return s
+}
+
+func saveSettings(p fyne.Preferences, s appSettings) {
+	p.SetString(entryBaseKey, s.EntryBase)
+	p.SetInt(workersKey, s.Workers)
+	p.SetString(mapKey, s.Map.String())
+	p.SetString(chartStyleKey, s.ChartStyle)
+	p.SetBool(heuristicsKey, s.Heuristics)
+	p.SetString(themeKey, s.Theme)
+	p.SetString(exportDirKey, s.ExportDir)
+}
+
+// oneOf returns s if it is one of the options and fallback if not
+func oneOf(s string, options []string, fallback string) string {
+	for _, o := range options {
+		if s == o {
+			return s
+		}
+	}
+	return fallback
+}
+
+// applySettings puts settings into effect. The worker pool grows or shrinks to
+// the new size and the charts are drawn again in the new style, with or without
+// the heuristics.
+func applySettings(s appSettings) {
+	setSettings(s)
+	fyne.CurrentApp().Settings().SetTheme(themeFor(s.Theme))
+	if workersThreadSafeSlice.Len() > 0 {
+		resizeWorkerPool(s.Workers)
+	}
+	for _, c := range []*chartView{stonesChart, stonesLogChart, sequenceLengthChart} {
+		if c != nil {
+			c.render()
+		}
+	}
+}
+
+// variantTheme is the default theme held to the light or dark variant
+type variantTheme struct {
+	fyne.Theme
+	variant fyne.ThemeVariant
+}
+
+func (t variantTheme) Color(name fyne.ThemeColorName, _ fyne.ThemeVariant) color.Color {
+	return t.Theme.Color(name, t.variant)
+}
+
+func themeFor(name string) fyne.Theme {
+	switch name {
+	case "Light":
+		return variantTheme{Theme: theme.DefaultTheme(), variant: theme.VariantLight}
+	case "Dark":
+		return variantTheme{Theme: theme.DefaultTheme(), variant: theme.VariantDark}
+	}
+	return theme.DefaultTheme()
+}
+
+// seriesStyle is the look of a hailstone chart in a chart style
+func seriesStyle(style string) chart.Style {
+	switch style {
+	case "Dots":
+		return chart.Style{StrokeWidth: chart.Disabled, DotWidth: 2}
+	case "Line and dots":
+		return chart.Style{DotWidth: 2}
+	}
+	return chart.Style{}
+}
+
+// The splits whose offsets are kept, by preference
+var splits = make(map[string]*container.Split)
+
+// rememberSplit sets a split to the offset it was left at and keeps it when the window closes
+func rememberSplit(key string, split *container.Split) {
+	split.Offset = fyne.CurrentApp().Preferences().FloatWithFallback(key, split.Offset)
+	splits[key] = split
+}
+
+// restoreWindow sizes the window as it was left and saves its size and splits when it closes
+func restoreWindow(w fyne.Window) {
+	p := fyne.CurrentApp().Preferences()
+	w.Resize(fyne.NewSize(float32(p.FloatWithFallback(windowWidthKey, 900)), float32(p.FloatWithFallback(windowHeightKey, 800))))
+
+	w.SetCloseIntercept(func() {
+		size := w.Canvas().Size()
+		p.SetFloat(windowWidthKey, float64(size.Width))
+		p.SetFloat(windowHeightKey, float64(size.Height))
+		for key, split := range splits {
+			p.SetFloat(key, split.Offset)
+		}
+		w.Close()
+	})
+}
+
+// showSettingsDialog edits the settings, which are saved and put into effect straight away
+func showSettingsDialog(win fyne.Window) {
+	s := currentSettings()
+
+	entryBase := widget.NewSelect(entryBases(true), nil)
+	entryBase.SetSelected(s.EntryBase)
+
+	workers := widget.NewEntry()
+	workers.SetText(strconv.Itoa(s.Workers))
+	workers.Validator = func(text string) error {
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 1 || n > maxWorkers {
+			return fmt.Errorf("the number of workers must be between 1 and %d", maxWorkers)
+		}
+		return nil
+	}
+
+	mapping := widget.NewSelect(collatzMapNames, nil)
+	mapping.SetSelected(s.Map.String())
+
+	chartStyle := widget.NewSelect(chartStyles, nil)
+	chartStyle.SetSelected(s.ChartStyle)
+
+	heuristics := widget.NewCheck("", nil)
+	heuristics.SetChecked(s.Heuristics)
+
+	themeSelect := widget.NewSelect(themeNames, nil)
+	themeSelect.SetSelected(s.Theme)
+
+	exportDir := widget.NewEntry()
+	exportDir.SetText(s.ExportDir)
+	browse := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		dialog.ShowFolderOpen(func(dir fyne.ListableURI, err error) {
+			if err == nil && dir != nil {
+				exportDir.SetText(dir.Path())
+			}
+		}, win)
+	})
+
+	dialog.ShowForm("Settings", "Save", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Entry Base", entryBase),
+		widget.NewFormItem("Workers", workers),
+		widget.NewFormItem("Map", mapping),
+		widget.NewFormItem("Chart Style", chartStyle),
+		widget.NewFormItem("Heuristics", heuristics),
+		widget.NewFormItem("Theme", themeSelect),
+		widget.NewFormItem("Export Folder", container.NewBorder(nil, nil, nil, browse, exportDir)),
+	}, func(save bool) {
+		if !save {
+			return
+		}
+		s.EntryBase = entryBase.Selected
+		s.Workers, _ = strconv.Atoi(workers.Text)
+		s.Map, _ = parseCollatzMap(mapping.Selected)
+		s.ChartStyle = chartStyle.Selected
+		s.Heuristics = heuristics.Checked
+		s.Theme = themeSelect.Selected
+		s.ExportDir = exportDir.Text
+
+		saveSettings(fyne.CurrentApp().Preferences(), s)
+		applySettings(s)
+		updateUI(func() {
+			singleEntryBase.SetSelected(s.EntryBase)
+			rangeEntryBase.SetSelected(s.EntryBase)
+		})
+	}, win)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+)
+
+func TestSettingsRoundTrip(t *testing.T) {
+	a := test.NewApp()
+	defer a.Quit()
+	p := a.Preferences()
+
+	if s := loadSettings(p); s != defaultSettings() {
+		t.Errorf("the settings with nothing saved are %+v, expected the defaults", s)
+	}
+
+	want := appSettings{EntryBase: "Base 16", Workers: 12, Map: shortcutMap, ChartStyle: "Dots", Heuristics: false, Theme: "Dark", ExportDir: t.TempDir()}
+	saveSettings(p, want)
+	if s := loadSettings(p); s != want {
+		t.Errorf("the settings read back are %+v, expected %+v", s, want)
+	}
+
+	// Anything not valid falls back to the default
+	p.SetString(entryBaseKey, "Base 99")
+	p.SetInt(workersKey, -3)
+	p.SetString(mapKey, "syracuse")
+	p.SetString(chartStyleKey, "Bars")
+	p.SetString(themeKey, "Purple")
+	d := defaultSettings()
+	d.ExportDir, d.Heuristics = want.ExportDir, want.Heuristics
+	if s := loadSettings(p); s != d {
+		t.Errorf("the settings read back are %+v, expected %+v", s, d)
+	}
+}
+
+func TestResizeWorkerPool(t *testing.T) {
+	newTestUI(t)
+	size := workersThreadSafeSlice.Len()
+	defer resizeWorkerPool(size)
+
+	resizeWorkerPool(size + 5)
+	if workersThreadSafeSlice.Len() != size+5 {
+		t.Errorf("the pool has %d workers, expected %d", workersThreadSafeSlice.Len(), size+5)
+	}
+	resizeWorkerPool(2)
+	if workersThreadSafeSlice.Len() != 2 {
+		t.Errorf("the pool has %d workers, expected 2", workersThreadSafeSlice.Len())
+	}
+
+	// The workers left still take values
+	results := make(chan sequenceProgress, 1)
+	var done sync.WaitGroup
+	done.Add(1)
+	workDistributorChannel <- workItem{value: *big.NewInt(27), mapping: standardMap, results: results, done: &done}
+	if report := <-results; report.steps != 111 {
+		t.Errorf("27 took %d steps in the smaller pool", report.steps)
+	}
+	done.Wait()
+}
+
+func TestSettingsApplyToSingleValue(t *testing.T) {
+	w, tabs := newTestUI(t)
+	s := currentSettings()
+	t.Cleanup(func() {
+		setSettings(s)
+	})
+
+	shortcut := s
+	shortcut.Map = shortcutMap
+	setSettings(shortcut)
+
+	entries := entriesOf(tabs.Items[0].Content)
+	singleEntryBase.SetSelected("Base 16")
+	test.Type(entries[0], "1b")
+	test.Tap(calcSingleBtn)
+	waitFor(t, "the shortcut map result", func() bool {
+		return seqLen.Text == "70"
+	})
+
+	// The base of a calculation is kept for the tab and not made the default
+	if base := currentSettings().EntryBase; base != s.EntryBase {
+		t.Errorf("the default entry base became %s, expected it to stay %s", base, s.EntryBase)
+	}
+
+	// A new window starts with the value calculated last, in its base
+	w2 := fyne.CurrentApp().NewWindow("Collatz Visualisation")
+	w2.SetContent(makeEntryTab(w2))
+	w2.Resize(fyne.NewSize(900, 800))
+	for _, o := range test.LaidOutObjects(w2.Content()) {
+		if tabs, ok := o.(*container.AppTabs); ok {
+			if entry := entriesOf(tabs.Items[0].Content)[0]; entry.Text != "1b" || singleEntryBase.Selected != "Base 16" {
+				t.Errorf("the value entry starts with %q in %s, expected 1b in Base 16", entry.Text, singleEntryBase.Selected)
+			}
+			if rangeEntryBase.Selected != s.EntryBase {
+				t.Errorf("the range entries start in %s, expected the default %s", rangeEntryBase.Selected, s.EntryBase)
+			}
+			break
+		}
+	}
+	w.Close()
+}
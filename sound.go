@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// The most stones played, a note each, the note the lowest stone plays and
+// the octaves the stones are spread across
+const maxSoundNotes = 10000
+const soundBaseNote = 48 // C3
+const soundOctaves = 3
+
+// The WAV sample rate and the fade at each end of a note, about 5ms, that stops it clicking
+const wavSampleRate = 44100
+const wavFadeSamples = 220
+
+// The MIDI ticks in a beat, each note lasting a beat
+const midiDivision = 480
+
+// The tempos that can be chosen, in notes a minute. Below 4 a beat is longer
+// than a MIDI file can hold.
+const minSoundTempo = 4
+const maxSoundTempo = 6000
+
+var soundFormats = []string{"WAV", "MIDI"}
+var pitchSources = []string{"Log2 Magnitude", "Parity"}
+var soundScaleNames = []string{"Major", "Minor Pentatonic", "Chromatic"}
+
+// The semitones above the root of each degree of the scales
+var soundScales = map[string][]int{
+	"Major":            {0, 2, 4, 5, 7, 9, 11},
+	"Minor Pentatonic": {0, 3, 5, 7, 10},
+	"Chromatic":        {0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+}
+
+// soundOptions are how a trajectory is played
+type soundOptions struct {
+	format string  // one of soundFormats
+	source string  // one of pitchSources
+	scale  string  // one of soundScaleNames
+	tempo  float64 // notes a minute
+}
+
+// soundNotes returns a MIDI note number for each of the first maxSoundNotes
+// stones. By magnitude the stones are spread over the octaves of the scale by
+// their log2, lowest to highest. By parity a stone reached by an even step
+// plays the root and one reached by an odd step the root an octave up.
+func soundNotes(t *trajectory, source string, scale string) []int {
+	degrees := soundScales[scale]
+	count := min(t.Len(), maxSoundNotes)
+
+	notes := make([]int, 0, count)
+	if source == "Parity" {
+		for i := 0; i < count; i++ {
+			if t.Upwards(i) {
+				notes = append(notes, soundBaseNote+12)
+			} else {
+				notes = append(notes, soundBaseNote)
+			}
+		}
+		return notes
+	}
+
+	logs := make([]float64, 0, count)
+	maxLog := 0.0
+	t.Walk(0, count, func(i int, stone *big.Int) {
+		l := log2Big(stone)
+		logs = append(logs, l)
+		maxLog = math.Max(maxLog, l)
+	})
+	top := soundOctaves*len(degrees) - 1
+	for _, l := range logs {
+		degree := 0
+		if maxLog > 0 {
+			degree = int(math.Round(l / maxLog * float64(top)))
+		}
+		notes = append(notes, soundBaseNote+12*(degree/len(degrees))+degrees[degree%len(degrees)])
+	}
+	return notes
+}
+
+// noteFrequency returns the frequency in Hz of a MIDI note, A4 being 440Hz
+func noteFrequency(note int) float64 {
+	return 440 * math.Pow(2, float64(note-69)/12)
+}
+
+// wavDataSize returns the bytes of samples the notes take, which the 32-bit
+// sizes in the header must be able to hold
+func wavDataSize(notes int, perNote int) (uint32, error) {
+	size := int64(notes) * int64(perNote) * 2
+	if size > math.MaxUint32-36 {
+		return 0, fmt.Errorf("%d notes of %d samples are more than a WAV file can hold, play them faster", notes, perNote)
+	}
+	return uint32(size), nil
+}
+
+// midiTempo returns the microseconds a beat of a tempo, which a Set Tempo event holds in 3 bytes
+func midiTempo(tempo float64) (uint32, error) {
+	usPerBeat := math.Round(60e6 / tempo)
+	if usPerBeat < 1 || usPerBeat > 0xffffff {
+		return 0, fmt.Errorf("a tempo of %g notes a minute cannot be written to a MIDI file", tempo)
+	}
+	return uint32(usPerBeat), nil
+}
+
+// writeWAV writes the notes as sine tones, one after another, to a 16-bit mono PCM WAV file
+func writeWAV(w io.Writer, notes []int, tempo float64) error {
+	perNote := int(wavSampleRate * 60 / tempo)
+	fade := min(wavFadeSamples, perNote/2)
+	dataSize, err := wavDataSize(len(notes), perNote)
+	if err != nil {
+		return err
+	}
+
+	header := struct {
+		RIFF          [4]byte
+		Size          uint32
+		WAVE          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		AudioFormat   uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF: [4]byte{'R', 'I', 'F', 'F'}, Size: 36 + dataSize, WAVE: [4]byte{'W', 'A', 'V', 'E'},
+		Fmt: [4]byte{'f', 'm', 't', ' '}, FmtSize: 16, AudioFormat: 1, Channels: 1,
+		SampleRate: wavSampleRate, ByteRate: wavSampleRate * 2, BlockAlign: 2, BitsPerSample: 16,
+		Data: [4]byte{'d', 'a', 't', 'a'}, DataSize: dataSize,
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := binary.Write(bw, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	samples := make([]int16, perNote)
+	for _, note := range notes {
+		step := 2 * math.Pi * noteFrequency(note) / wavSampleRate
+		for i := range samples {
+			gain := 0.5
+			if i < fade {
+				gain *= float64(i) / float64(fade)
+			} else if i >= perNote-fade {
+				gain *= float64(perNote-1-i) / float64(fade)
+			}
+			samples[i] = int16(gain * math.MaxInt16 * math.Sin(step*float64(i)))
+		}
+		if err := binary.Write(bw, binary.LittleEndian, samples); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeMIDI writes the notes, a beat each, to a Standard MIDI File of one track
+func writeMIDI(w io.Writer, notes []int, tempo float64) error {
+	usPerBeat, err := midiTempo(tempo)
+	if err != nil {
+		return err
+	}
+
+	var track []byte
+	event := func(delta uint32, data ...byte) {
+		track = appendVarLen(track, delta)
+		track = append(track, data...)
+	}
+
+	// The tempo in microseconds a beat, then a piano on channel 1
+	event(0, 0xff, 0x51, 0x03, byte(usPerBeat>>16), byte(usPerBeat>>8), byte(usPerBeat))
+	event(0, 0xc0, 0x00)
+	for _, note := range notes {
+		event(0, 0x90, byte(note), 0x60)
+		event(midiDivision, 0x80, byte(note), 0x00)
+	}
+	event(0, 0xff, 0x2f, 0x00)
+
+	bw := bufio.NewWriter(w)
+	for _, chunk := range []any{
+		[]byte("MThd"), uint32(6), uint16(0), uint16(1), uint16(midiDivision),
+		[]byte("MTrk"), uint32(len(track)), track,
+	} {
+		if err := binary.Write(bw, binary.BigEndian, chunk); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// appendVarLen appends a MIDI variable-length quantity, seven bits a byte, most significant first
+func appendVarLen(b []byte, v uint32) []byte {
+	var groups []byte
+	for {
+		groups = append(groups, byte(v&0x7f))
+		v >>= 7
+		if v == 0 {
+			break
+		}
+	}
+	for i := len(groups) - 1; i >= 0; i-- {
+		if i > 0 {
+			b = append(b, groups[i]|0x80)
+		} else {
+			b = append(b, groups[i])
+		}
+	}
+	return b
+}
+
+// exportSound saves a trajectory to the export folder as a WAV or MIDI file
+func exportSound(report *sequenceProgress, o soundOptions, win fyne.Window) {
+	notes := soundNotes(report.trajectory, o.source, o.scale)
+
+	write, ext := writeWAV, "wav"
+	if o.format == "MIDI" {
+		write, ext = writeMIDI, "mid"
+	}
+	name := filepath.Join(currentSettings().ExportDir, fmt.Sprintf("collatz_%s.%s", abbreviateName(report.number), ext))
+	f, err := os.Create(name)
+	if err == nil {
+		err = write(f, notes, o.tempo)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		showInformation("Export Error", fmt.Sprintf("The sound could not be saved: %v", err), win)
+		return
+	}
+
+	message := fmt.Sprintf("%d notes were saved to %s", len(notes), name)
+	if len(notes) < report.trajectory.Len() {
+		message += fmt.Sprintf(", the first %d stones of %d", len(notes), report.trajectory.Len())
+	}
+	showInformation("Export", message, win)
+}
+
+// abbreviateName returns values for a file name in the display base, the last
+// digits only of any that are long. A base other than 10 is added to the name,
+// so 11011 in binary is not saved over 11011 in decimal.
+func abbreviateName(values ...*big.Int) string {
+	names := make([]string, len(values))
+	for i, n := range values {
+		s := formatValue(n)
+		if len(s) > 40 {
+			s = fmt.Sprintf("%d_digits_%s", len(s), s[len(s)-20:])
+		}
+		names[i] = s
+	}
+	name := strings.Join(names, "_")
+	if base := displayBase.Load(); base != 0 && base != 10 {
+		name += fmt.Sprintf("_base%d", base)
+	}
+	return name
+}
+
+// showSoundDialog asks how the trajectory shown on the Single Value tab is played and exports it
+func showSoundDialog(report *sequenceProgress, win fyne.Window) {
+	if report == nil || !report.lastStone {
+		dialog.ShowInformation("Export Sound", "Calculate a value first", win)
+		return
+	}
+
+	format := widget.NewSelect(soundFormats, nil)
+	format.SetSelected(soundFormats[0])
+	source := widget.NewSelect(pitchSources, nil)
+	source.SetSelected(pitchSources[0])
+	scale := widget.NewSelect(soundScaleNames, nil)
+	scale.SetSelected(soundScaleNames[0])
+	tempo := widget.NewEntry()
+	tempo.SetText("240")
+	tempo.Validator = func(s string) error {
+		if v, err := strconv.ParseFloat(s, 64); err != nil || v < minSoundTempo || v > maxSoundTempo {
+			return fmt.Errorf("the tempo must be between %d and %d notes a minute", minSoundTempo, maxSoundTempo)
+		}
+		return nil
+	}
+
+	dialog.ShowForm("Export Sound", "Export", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Format", format),
+		widget.NewFormItem("Pitch From", source),
+		widget.NewFormItem("Scale", scale),
+		widget.NewFormItem("Notes a Minute", tempo),
+	}, func(export bool) {
+		if !export {
+			return
+		}
+		o := soundOptions{format: format.Selected, source: source.Selected, scale: scale.Selected}
+		o.tempo, _ = strconv.ParseFloat(tempo.Text, 64)
+		go exportSound(report, o, win)
+	}, win)
+}
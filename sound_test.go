@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestSoundNotes(t *testing.T) {
+	traj := Collatz(*big.NewInt(5), standardMap, nil, 0).trajectory
+
+	// 5, 16, 8, 4, 2, 1 by log2 over three octaves of the major scale from C3, 16 at the top
+	if notes := soundNotes(traj, "Log2 Magnitude", "Major"); !reflect.DeepEqual(notes, []int{69, 83, 74, 65, 57, 48}) {
+		t.Errorf("the notes by magnitude are %v", notes)
+	}
+	// Only the step from 5 to 16 is odd
+	if notes := soundNotes(traj, "Parity", "Major"); !reflect.DeepEqual(notes, []int{48, 60, 48, 48, 48, 48}) {
+		t.Errorf("the notes by parity are %v", notes)
+	}
+	// 1 has a single stone at the bottom of the scale
+	if notes := soundNotes(Collatz(*big.NewInt(1), standardMap, nil, 0).trajectory, "Log2 Magnitude", "Chromatic"); !reflect.DeepEqual(notes, []int{48}) {
+		t.Errorf("the notes of 1 are %v", notes)
+	}
+}
+
+func TestWriteWAV(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := writeWAV(&buffer, []int{48, 69, 60}, 600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A tenth of a second a note
+	perNote := wavSampleRate / 10
+	if buffer.Len() != 44+3*perNote*2 {
+		t.Fatalf("the file is %d bytes, expected %d", buffer.Len(), 44+3*perNote*2)
+	}
+	b := buffer.Bytes()
+	if string(b[0:4]) != "RIFF" || string(b[8:16]) != "WAVEfmt " || string(b[36:40]) != "data" ||
+		binary.LittleEndian.Uint32(b[4:]) != uint32(buffer.Len()-8) || binary.LittleEndian.Uint32(b[24:]) != wavSampleRate ||
+		binary.LittleEndian.Uint32(b[40:]) != uint32(3*perNote*2) {
+		t.Errorf("the header is % x", b[:44])
+	}
+
+	// A4 at 440Hz crosses zero 88 times in a tenth of a second
+	samples := make([]int16, perNote)
+	binary.Read(bytes.NewReader(b[44+perNote*2:]), binary.LittleEndian, samples)
+	crossings := 0
+	for i := 1; i < len(samples); i++ {
+		if (samples[i-1] < 0) != (samples[i] < 0) {
+			crossings++
+		}
+	}
+	if crossings < 86 || crossings > 90 {
+		t.Errorf("the second note crosses zero %d times, expected 88", crossings)
+	}
+}
+
+func TestWriteMIDI(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := writeMIDI(&buffer, []int{48, 69, 60}, 120); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buffer.Bytes()
+	if string(b[0:4]) != "MThd" || binary.BigEndian.Uint32(b[4:]) != 6 || binary.BigEndian.Uint16(b[8:]) != 0 ||
+		binary.BigEndian.Uint16(b[10:]) != 1 || binary.BigEndian.Uint16(b[12:]) != midiDivision || string(b[14:18]) != "MTrk" {
+		t.Fatalf("the header is % x", b[:18])
+	}
+	track := b[22:]
+	if int(binary.BigEndian.Uint32(b[18:])) != len(track) {
+		t.Fatalf("the track is %d bytes, its header says %d", len(track), binary.BigEndian.Uint32(b[18:]))
+	}
+
+	// 500000 microseconds a beat at 120 a minute, a piano, then each note on for a beat
+	want := []byte{0x00, 0xff, 0x51, 0x03, 0x07, 0xa1, 0x20, 0x00, 0xc0, 0x00}
+	for _, note := range []byte{48, 69, 60} {
+		want = append(want, 0x00, 0x90, note, 0x60, 0x83, 0x60, 0x80, note, 0x00)
+	}
+	want = append(want, 0x00, 0xff, 0x2f, 0x00)
+	if !bytes.Equal(track, want) {
+		t.Errorf("the track is % x, expected % x", track, want)
+	}
+}
+
+func TestSoundLimits(t *testing.T) {
+	// The header sizes hold up to 2^32-1 bytes, 36 of them before the samples
+	fits := (math.MaxUint32 - 36) / 2
+	if size, err := wavDataSize(1, fits); err != nil || size != uint32(fits*2) {
+		t.Errorf("%d samples take %d bytes (%v), expected %d", fits, size, err, fits*2)
+	}
+	if _, err := wavDataSize(1, fits+1); err == nil {
+		t.Errorf("%d samples were accepted", fits+1)
+	}
+	if _, err := wavDataSize(maxSoundNotes, wavSampleRate*60/minSoundTempo); err == nil {
+		t.Errorf("%d notes at %d a minute were accepted", maxSoundNotes, minSoundTempo)
+	}
+	if err := writeWAV(io.Discard, make([]int, maxSoundNotes), 10); err == nil {
+		t.Error("a WAV file of more than 4 GiB was written")
+	}
+
+	// A Set Tempo event holds up to 0xffffff microseconds a beat
+	for tempo, want := range map[float64]uint32{minSoundTempo: 15000000, 3.58: 16759777, maxSoundTempo: 10000, 120: 500000} {
+		if got, err := midiTempo(tempo); err != nil || got != want {
+			t.Errorf("a tempo of %g is %d microseconds a beat (%v), expected %d", tempo, got, err, want)
+		}
+	}
+	for _, tempo := range []float64{3.57, 1, 0, 1e9} {
+		if _, err := midiTempo(tempo); err == nil {
+			t.Errorf("a tempo of %g was accepted", tempo)
+		}
+	}
+	if err := writeMIDI(io.Discard, []int{48}, 1); err == nil {
+		t.Error("a MIDI file at 1 note a minute was written")
+	}
+}
+
+func TestAppendVarLen(t *testing.T) {
+	for v, want := range map[uint32][]byte{
+		0:          {0x00},
+		0x7f:       {0x7f},
+		0x80:       {0x81, 0x00},
+		480:        {0x83, 0x60},
+		0x0fffffff: {0xff, 0xff, 0xff, 0x7f},
+	} {
+		if got := appendVarLen(nil, v); !bytes.Equal(got, want) {
+			t.Errorf("%#x is % x, expected % x", v, got, want)
+		}
+	}
+}
+
+func TestExportSound(t *testing.T) {
+	a := test.NewApp()
+	defer a.Quit()
+
+	s := defaultSettings()
+	s.ExportDir = t.TempDir()
+	setSettings(s)
+	defer setSettings(defaultSettings())
+
+	report := Collatz(*big.NewInt(27), standardMap, nil, 0)
+	w := a.NewWindow("")
+	for _, o := range []struct{ format, name string }{{"WAV", "collatz_27.wav"}, {"MIDI", "collatz_27.mid"}} {
+		exportSound(&report, soundOptions{format: o.format, source: "Parity", scale: "Major", tempo: 600}, w)
+		waitForUI()
+		if info, err := os.Stat(filepath.Join(s.ExportDir, o.name)); err != nil || info.Size() == 0 {
+			t.Errorf("%s was not saved: %v", o.name, err)
+		}
+	}
+}
+
+func TestAbbreviateName(t *testing.T) {
+	t.Cleanup(func() {
+		displayBase.Store(10)
+	})
+
+	long, _ := new(big.Int).SetString(strings.Repeat("9", 45), 10)
+	for _, o := range []struct {
+		base   int32
+		values []*big.Int
+		want   string
+	}{
+		{10, []*big.Int{big.NewInt(27)}, "27"},
+		{10, []*big.Int{big.NewInt(1), big.NewInt(200)}, "1_200"},
+		{10, []*big.Int{long}, "45_digits_" + strings.Repeat("9", 20)},
+		{2, []*big.Int{big.NewInt(27)}, "11011_base2"},
+		{16, []*big.Int{big.NewInt(1), big.NewInt(200)}, "1_c8_base16"},
+	} {
+		displayBase.Store(o.base)
+		if got := abbreviateName(o.values...); got != o.want {
+			t.Errorf("%v in base %d is named %s, expected %s", o.values, o.base, got, o.want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const storeFile = "results.log"
+
+// storeEntry is one computed value in the results database
+type storeEntry struct {
+	Number       string `json:"n"`
+	Steps        int    `json:"steps"`
+	StoppingTime int    `json:"stoppingTime"`
+	MaxStone     string `json:"maxStone"`
+	ParityHash   string `json:"parityHash"`
+}
+
+// storeQuery selects entries from the results database. Zero values are not applied.
+type storeQuery struct {
+	MinSteps        int
+	MaxSteps        int
+	MinStoppingTime int
+	MaxStoppingTime int
+	ParityHash      string
+	Limit           int
+}
+
+// The most values the index holds, about 40 bytes each. Values computed once it
+// is full are not stored.
+var maxStoredResults = 10000000
+
+var errStoreReadOnly = errors.New("the results database is open read only")
+
+// resultStore is an append-only log of JSON lines, one per computed value,
+// with an in-memory index from a hash of the decimal value to the offset of
+// its line in the log. The index is rebuilt by scanning the log when the store
+// is opened. A value whose hash is already taken by another is not stored.
+//
+// One process at a time opens the log for writing, holding a lock on it while
+// it is open. Others may open it read only, and never change the file.
+type resultStore struct {
+	sync.Mutex
+	file     *os.File
+	writer   *bufio.Writer
+	readOnly bool
+	size     int64
+	index    map[uint64]int64
+	hits     int64
+	misses   int64
+}
+
+var results *resultStore
+
+// openResultStore opens the log at path for writing, which fails while another
+// process has it open for writing, or read only
+func openResultStore(path string, readOnly bool) (*resultStore, error) {
+	flag := os.O_RDWR | os.O_CREATE
+	if readOnly {
+		flag = os.O_RDONLY
+	}
+	file, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if !readOnly {
+		if err := lockFile(file); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("the results database is in use by another process: %v", err)
+		}
+	}
+
+	s := &resultStore{file: file, readOnly: readOnly, index: make(map[uint64]int64)}
+
+	// Rebuild the index, stopping at the first line that cannot be read
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var e storeEntry
+		if json.Unmarshal(line, &e) != nil {
+			break
+		}
+		if len(s.index) < maxStoredResults {
+			s.index[storeKey(e.Number)] = s.size
+		}
+		s.size += int64(len(line))
+	}
+	if readOnly {
+		return s, nil
+	}
+
+	// Drop anything after the last good line, such as a write cut short by a crash
+	if err := file.Truncate(s.size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(s.size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	s.writer = bufio.NewWriter(file)
+	return s, nil
+}
+
+// storeKey hashes a decimal value for the index
+func storeKey(number string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(number))
+	return h.Sum64()
+}
+
+// openDefaultResultStore opens the results database in the application storage directory
+func openDefaultResultStore(readOnly bool) (*resultStore, error) {
+	path, err := storagePath(storeFile)
+	if err != nil {
+		return nil, err
+	}
+	return openResultStore(path, readOnly)
+}
+
+func newStoreEntry(report sequenceProgress) storeEntry {
+	return storeEntry{
+		Number:       report.number.String(),
+		Steps:        report.steps,
+		StoppingTime: report.stoppingTime,
+		MaxStone:     report.maxStoneInt.String(),
+		ParityHash:   strconv.FormatUint(report.parityHash, 16),
+	}
+}
+
+// progress converts the entry back into the summary CollatzPerf would have produced
+func (e storeEntry) progress() (report sequenceProgress, err error) {
+	n, ok := new(big.Int).SetString(e.Number, 10)
+	if !ok {
+		return report, fmt.Errorf("stored value %q is not a number", e.Number)
+	}
+	m, ok := new(big.Int).SetString(e.MaxStone, 10)
+	if !ok {
+		return report, fmt.Errorf("stored max stone %q is not a number", e.MaxStone)
+	}
+	h, err := strconv.ParseUint(e.ParityHash, 16, 64)
+	if err != nil {
+		return report, err
+	}
+	report = sequenceProgress{maxStoneInt: m, steps: e.Steps, stoppingTime: e.StoppingTime, parityHash: h, maxStoneString: e.MaxStone, number: n}
+	return report, nil
+}
+
+func (s *resultStore) Len() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return len(s.index)
+}
+
+// Stats returns the number of lookups that were and were not found
+func (s *resultStore) Stats() (hits int64, misses int64) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.hits, s.misses
+}
+
+// Get returns the stored entry for n, counting the lookup as a hit or a miss
+func (s *resultStore) Get(n *big.Int) (storeEntry, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	e, ok, err := s.read(n.String())
+	if err != nil || !ok {
+		s.misses++
+		return e, false
+	}
+	s.hits++
+	return e, true
+}
+
+// Put appends the entry to the log unless the value is already stored
+func (s *resultStore) Put(e storeEntry) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.readOnly {
+		return errStoreReadOnly
+	}
+	key := storeKey(e.Number)
+	if _, ok := s.index[key]; ok {
+		return nil
+	}
+	if len(s.index) >= maxStoredResults {
+		return fmt.Errorf("the results database is full at %d values", maxStoredResults)
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := s.writer.Write(line); err != nil {
+		return err
+	}
+	s.index[key] = s.size
+	s.size += int64(len(line))
+	return nil
+}
+
+// Query scans the log in insertion order and returns the entries matching q
+func (s *resultStore) Query(q storeQuery) ([]storeEntry, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.flush(); err != nil {
+		return nil, err
+	}
+
+	matches := make([]storeEntry, 0)
+	reader := bufio.NewReader(io.NewSectionReader(s.file, 0, s.size))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return matches, err
+		}
+		var e storeEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return matches, err
+		}
+		if q.matches(e) {
+			matches = append(matches, e)
+			if q.Limit > 0 && len(matches) >= q.Limit {
+				break
+			}
+		}
+	}
+	return matches, nil
+}
+
+func (s *resultStore) Flush() error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.flush()
+}
+
+func (s *resultStore) flush() error {
+	if s.writer == nil {
+		return nil
+	}
+	return s.writer.Flush()
+}
+
+func (s *resultStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+	return s.file.Close()
+}
+
+// read returns the entry for a decimal value, which is not found when another
+// value has its hash
+func (s *resultStore) read(number string) (e storeEntry, ok bool, err error) {
+	offset, ok := s.index[storeKey(number)]
+	if !ok {
+		return e, false, nil
+	}
+	if s.writer != nil && s.writer.Buffered() > 0 {
+		if err := s.writer.Flush(); err != nil {
+			return e, false, err
+		}
+	}
+	line, err := bufio.NewReader(io.NewSectionReader(s.file, offset, s.size-offset)).ReadBytes('\n')
+	if err != nil {
+		return e, false, err
+	}
+	if err := json.Unmarshal(line, &e); err != nil {
+		return e, false, err
+	}
+	return e, e.Number == number, nil
+}
+
+func (q storeQuery) matches(e storeEntry) bool {
+	if q.MinSteps > 0 && e.Steps < q.MinSteps {
+		return false
+	}
+	if q.MaxSteps > 0 && e.Steps > q.MaxSteps {
+		return false
+	}
+	if q.MinStoppingTime > 0 && e.StoppingTime < q.MinStoppingTime {
+		return false
+	}
+	if q.MaxStoppingTime > 0 && e.StoppingTime > q.MaxStoppingTime {
+		return false
+	}
+	if q.ParityHash != "" && e.ParityHash != q.ParityHash {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// openTestStore opens a results database in a temporary directory
+func openTestStore(t *testing.T, path string) *resultStore {
+	t.Helper()
+	store, err := openResultStore(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// storeValues puts the results of the values from lower to upper into the store
+func storeValues(t *testing.T, store *resultStore, lower int64, upper int64) {
+	t.Helper()
+	for i := lower; i <= upper; i++ {
+		if err := store.Put(newStoreEntry(CollatzPerf(*big.NewInt(i), standardMap))); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), storeFile))
+	defer store.Close()
+	storeValues(t, store, 1, 100)
+
+	// A value put again is not stored twice
+	storeValues(t, store, 27, 27)
+	if store.Len() != 100 {
+		t.Errorf("the store has %d entries, expected 100", store.Len())
+	}
+
+	e, ok := store.Get(big.NewInt(27))
+	if !ok {
+		t.Fatal("27 is not in the store")
+	}
+	got, err := e.progress()
+	want := CollatzPerf(*big.NewInt(27), standardMap)
+	if err != nil || got.number.Int64() != 27 || got.steps != want.steps || got.stoppingTime != want.stoppingTime || got.maxStoneString != "9232" || got.parityHash != want.parityHash {
+		t.Errorf("27 was stored as %+v (%v), expected %+v", got, err, want)
+	}
+
+	if _, ok := store.Get(big.NewInt(101)); ok {
+		t.Error("101 was found in the store")
+	}
+	if hits, misses := store.Stats(); hits != 1 || misses != 1 {
+		t.Errorf("%d hits and %d misses, expected 1 of each", hits, misses)
+	}
+}
+
+func TestStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), storeFile)
+	store := openTestStore(t, path)
+	storeValues(t, store, 1, 100)
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The index is rebuilt from the log, and new entries go after the old
+	store = openTestStore(t, path)
+	defer store.Close()
+	if store.Len() != 100 {
+		t.Errorf("the reopened store has %d entries, expected 100", store.Len())
+	}
+	if e, ok := store.Get(big.NewInt(97)); !ok || e.Steps != 118 {
+		t.Errorf("97 was found %v with %d steps, expected 118", ok, e.Steps)
+	}
+	storeValues(t, store, 101, 110)
+	if e, ok := store.Get(big.NewInt(110)); !ok || e.Steps != 113 || store.Len() != 110 {
+		t.Errorf("110 was found %v with %d steps among %d entries, expected 113 among 110", ok, e.Steps, store.Len())
+	}
+}
+
+func TestStoreTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), storeFile)
+	store := openTestStore(t, path)
+	storeValues(t, store, 1, 10)
+	store.Close()
+	good, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A write cut short by a crash leaves part of a line at the end
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"n":"11","steps":1`)
+	file.Close()
+
+	store = openTestStore(t, path)
+	if store.Len() != 10 {
+		t.Errorf("the store has %d entries, expected the 10 before the cut", store.Len())
+	}
+	if info, _ := os.Stat(path); info.Size() != good.Size() {
+		t.Errorf("the log is %d bytes, expected the part line to be dropped to leave %d", info.Size(), good.Size())
+	}
+	storeValues(t, store, 11, 12)
+	store.Close()
+
+	store = openTestStore(t, path)
+	defer store.Close()
+	if e, ok := store.Get(big.NewInt(11)); !ok || e.Steps != 14 || store.Len() != 12 {
+		t.Errorf("11 was found %v with %d steps among %d entries, expected 14 among 12", ok, e.Steps, store.Len())
+	}
+}
+
+func TestStoreQuery(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), storeFile))
+	defer store.Close()
+	storeValues(t, store, 1, 1000)
+
+	numbers := func(entries []storeEntry) []string {
+		var n []string
+		for _, e := range entries {
+			n = append(n, e.Number)
+		}
+		return n
+	}
+	hash27 := newStoreEntry(CollatzPerf(*big.NewInt(27), standardMap)).ParityHash
+
+	for _, test := range []struct {
+		query storeQuery
+		want  []string
+	}{
+		{storeQuery{MinSteps: 170}, []string{"703", "871", "937"}},
+		{storeQuery{MinSteps: 170, MaxSteps: 175}, []string{"703", "937"}},
+		{storeQuery{MinSteps: 170, Limit: 2}, []string{"703", "871"}},
+		{storeQuery{MaxSteps: 1}, []string{"1", "2"}},
+		{storeQuery{MinStoppingTime: 96, Limit: 1}, []string{"27"}},
+		{storeQuery{MinStoppingTime: 96, MaxStoppingTime: 96, MaxSteps: 111}, []string{"27"}},
+		{storeQuery{MinSteps: 200}, nil},
+		{storeQuery{ParityHash: hash27}, []string{"27"}},
+	} {
+		got, err := store.Query(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g := numbers(got); !reflect.DeepEqual(g, test.want) {
+			t.Errorf("the query %+v found %v, expected %v", test.query, g, test.want)
+		}
+	}
+}
+
+func TestSessionCountsStoreFailures(t *testing.T) {
+	startTestPool()
+	store := openTestStore(t, filepath.Join(t.TempDir(), storeFile))
+	store.file.Close()
+
+	tracker, _ := newSweepTracker(big.NewInt(1), big.NewInt(1001))
+	session := newRunSession(tracker, standardMap, store)
+	session.Run()
+
+	// The buffered writer fails once it first has to write to the closed file
+	snap := session.Snapshot()
+	if snap.Status != sessionFinished || snap.Processed != 1000 || snap.StoreFailures == 0 || snap.StoreError == nil {
+		t.Errorf("the session is %s after %d values with %d results not stored (%v), expected some not stored", snap.Status, snap.Processed, snap.StoreFailures, snap.StoreError)
+	}
+}
+
+func TestStoreLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), storeFile)
+	writer := openTestStore(t, path)
+	defer writer.Close()
+	storeValues(t, writer, 1, 10)
+	writer.Flush()
+
+	// Only one process writes at a time
+	if _, err := openResultStore(path, false); err == nil {
+		t.Fatal("the store was opened for writing twice")
+	}
+
+	// A reader sees the lines written so far and leaves a line being written alone
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"n":"11","steps":1`)
+	file.Close()
+	before, _ := os.Stat(path)
+
+	reader, err := openResultStore(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if reader.Len() != 10 {
+		t.Errorf("the reader found %d entries, expected 10", reader.Len())
+	}
+	if e, ok := reader.Get(big.NewInt(9)); !ok || e.Steps != 19 {
+		t.Errorf("9 was found %v with %d steps, expected 19", ok, e.Steps)
+	}
+	if after, _ := os.Stat(path); after.Size() != before.Size() {
+		t.Errorf("the reader cut the log from %d to %d bytes", before.Size(), after.Size())
+	}
+	if err := reader.Put(newStoreEntry(CollatzPerf(*big.NewInt(12), standardMap))); err == nil {
+		t.Error("a value was put into a store open read only")
+	}
+}
+
+func TestStoreFull(t *testing.T) {
+	saved := maxStoredResults
+	t.Cleanup(func() {
+		maxStoredResults = saved
+	})
+	maxStoredResults = 5
+
+	path := filepath.Join(t.TempDir(), storeFile)
+	store := openTestStore(t, path)
+	storeValues(t, store, 1, 5)
+	if err := store.Put(newStoreEntry(CollatzPerf(*big.NewInt(6), standardMap))); err == nil || store.Len() != 5 {
+		t.Errorf("a sixth value was stored (%v) in a store of %d", err, store.Len())
+	}
+	store.Close()
+
+	store = openTestStore(t, path)
+	defer store.Close()
+	if _, ok := store.Get(big.NewInt(5)); !ok || store.Len() != 5 {
+		t.Errorf("the reopened store has %d values, expected 5", store.Len())
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math/big"
+)
+
+// The number of steps between the stones a trajectory keeps
+const trajectoryCheckpointInterval = 1024
+
+// trajectory is the sequence of a value held as its parity vector, one bit a
+// step, and a stone every trajectoryCheckpointInterval steps. Any other stone
+// is regenerated from the checkpoint before it, so a trajectory takes memory
+// in proportion to its steps rather than to its steps times the stone size.
+type trajectory struct {
+	mapping     collatzMap
+	parity      []uint64
+	steps       int
+	checkpoints []*big.Int
+}
+
+func newTrajectory(start *big.Int, m collatzMap) *trajectory {
+	return &trajectory{mapping: m, checkpoints: []*big.Int{new(big.Int).Set(start)}}
+}
+
+// record adds a step that produced stone, which was odd when it went up
+func (t *trajectory) record(odd bool, stone *big.Int) {
+	if t.steps%64 == 0 {
+		t.parity = append(t.parity, 0)
+	}
+	if odd {
+		t.parity[t.steps/64] |= 1 << (t.steps % 64)
+	}
+	t.steps++
+	if t.steps%trajectoryCheckpointInterval == 0 {
+		t.checkpoints = append(t.checkpoints, new(big.Int).Set(stone))
+	}
+}
+
+// snapshot returns the trajectory so far. Later steps only append, so the copy stays valid.
+func (t *trajectory) snapshot() *trajectory {
+	c := *t
+	return &c
+}
+
+// Len returns the number of stones, including the start value
+func (t *trajectory) Len() int {
+	return t.steps + 1
+}
+
+// Upwards reports whether stone i is above stone i-1, that is whether step i-1 was odd
+func (t *trajectory) Upwards(i int) bool {
+	if i < 1 || i > t.steps {
+		return false
+	}
+	step := i - 1
+	return t.parity[step/64]&(1<<(step%64)) != 0
+}
+
+// Walk regenerates stones [from, to) and hands each to f, which must not keep or change it
+func (t *trajectory) Walk(from int, to int, f func(i int, stone *big.Int)) {
+	if from < 0 {
+		from = 0
+	}
+	if to > t.Len() {
+		to = t.Len()
+	}
+	if from >= to {
+		return
+	}
+
+	i := from - from%trajectoryCheckpointInterval
+	stone := new(big.Int).Set(t.checkpoints[i/trajectoryCheckpointInterval])
+	for ; i < to; i++ {
+		if i >= from {
+			f(i, stone)
+		}
+		if i+1 < to {
+			if t.Upwards(i + 1) {
+				t.mapping.oddStep(stone)
+			} else {
+				stone.Rsh(stone, 1)
+			}
+		}
+	}
+}
+
+// Stones returns copies of stones [from, from+count)
+func (t *trajectory) Stones(from int, count int) []*big.Int {
+	var stones []*big.Int
+	t.Walk(from, from+count, func(i int, stone *big.Int) {
+		stones = append(stones, new(big.Int).Set(stone))
+	})
+	return stones
+}
+
+// Sample walks stones [from, to) and hands f at most maxPoints of them,
+// evenly spaced and always including the first and last
+func (t *trajectory) Sample(from int, to int, maxPoints int, f func(i int, stone *big.Int)) {
+	if from < 0 {
+		from = 0
+	}
+	if to > t.Len() {
+		to = t.Len()
+	}
+	stride := 1
+	if maxPoints > 1 && to-from > maxPoints {
+		stride = (to - from + maxPoints - 3) / (maxPoints - 1)
+	}
+	last := to - 1
+	t.Walk(from, to, func(i int, stone *big.Int) {
+		if (i-from)%stride == 0 || i == last {
+			f(i, stone)
+		}
+	})
+}
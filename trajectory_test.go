@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+// naiveTrajectory lists every stone of n by running the map
+func naiveTrajectory(n *big.Int, m collatzMap) []*big.Int {
+	stone := new(big.Int).Set(n)
+	stones := []*big.Int{new(big.Int).Set(stone)}
+	for stone.Cmp(oneBig) != 0 {
+		if stone.Bit(0) == 1 {
+			m.oddStep(stone)
+		} else {
+			stone.Rsh(stone, 1)
+		}
+		stones = append(stones, new(big.Int).Set(stone))
+	}
+	return stones
+}
+
+func TestTrajectoryRegeneratesStones(t *testing.T) {
+	// 300 bit values take a few thousand steps, so pages cross checkpoints
+	for _, m := range []collatzMap{standardMap, shortcutMap} {
+		for _, n := range append(randomValues(10, 300), big.NewInt(27)) {
+			want := naiveTrajectory(n, m)
+			tr := Collatz(copyOf(n), m, nil, 100).trajectory
+
+			if tr.Len() != len(want) {
+				t.Fatalf("%s map, %s: %d stones, expected %d", m, n, tr.Len(), len(want))
+			}
+			for _, from := range []int{0, 1, trajectoryCheckpointInterval - 1, trajectoryCheckpointInterval, len(want) - 3} {
+				for idx, stone := range tr.Stones(from, 5) {
+					if i := from + idx; i >= 0 && stone.Cmp(want[i]) != 0 {
+						t.Errorf("%s map, %s: stone %d is %s, expected %s", m, n, i, stone, want[i])
+					}
+				}
+			}
+			for i := 1; i < len(want); i++ {
+				if tr.Upwards(i) != (want[i].Cmp(want[i-1]) == 1) {
+					t.Errorf("%s map, %s: stone %d upwards is %v", m, n, i, tr.Upwards(i))
+				}
+			}
+		}
+	}
+}
+
+func TestTrajectorySample(t *testing.T) {
+	tr := Collatz(copyOf(benchmarkValues["ThousandBit"]), standardMap, nil, 100).trajectory
+
+	var points []int
+	tr.Sample(0, tr.Len(), maxChartPoints, func(i int, stone *big.Int) {
+		points = append(points, i)
+	})
+	if len(points) > maxChartPoints {
+		t.Errorf("%d points sampled, at most %d expected", len(points), maxChartPoints)
+	}
+	if points[0] != 0 || points[len(points)-1] != tr.Len()-1 {
+		t.Errorf("the samples run from %d to %d, expected 0 to %d", points[0], points[len(points)-1], tr.Len()-1)
+	}
+
+	// A window of the trajectory is sampled the same way
+	points = nil
+	tr.Sample(5000, 5100, 30, func(i int, stone *big.Int) {
+		points = append(points, i)
+	})
+	if len(points) > 30 || points[0] != 5000 || points[len(points)-1] != 5099 {
+		t.Errorf("the window samples are %v", points)
+	}
+
+	all := 0
+	Collatz(*big.NewInt(27), standardMap, nil, 100).trajectory.Sample(0, 112, maxChartPoints, func(i int, stone *big.Int) {
+		all++
+	})
+	if all != 112 {
+		t.Errorf("%d stones of 27 sampled, expected all 112", all)
+	}
+}
+
+func TestCollatzReportsTrajectory(t *testing.T) {
+	reports := make(chan sequenceProgress, 100)
+	final := Collatz(*big.NewInt(27), standardMap, reports, 10)
+	close(reports)
+
+	count := 0
+	for report := range reports {
+		count++
+		if report.trajectory.Len() != report.steps+1 {
+			t.Errorf("a report after %d steps has %d stones", report.steps, report.trajectory.Len())
+		}
+	}
+	if count != 12 {
+		t.Errorf("%d reports sent, expected 12", count)
+	}
+	if final.trajectory.Len() != 112 {
+		t.Errorf("the final trajectory has %d stones", final.trajectory.Len())
+	}
+}
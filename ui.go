@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math/big"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+)
+
+// Widgets are changed on a single goroutine. The engine goroutines hand their
+// changes to updateUI, which applies them one at a time in the order given.
+var uiUpdates = make(chan func(), 1000)
+var uiUpdatesOnce sync.Once
+
+func handleUIUpdates() {
+	for update := range uiUpdates {
+		update()
+	}
+}
+
+// updateUI queues a change to the widgets. It must not be called from within an update.
+func updateUI(update func()) {
+	uiUpdatesOnce.Do(func() {
+		go handleUIUpdates()
+	})
+	uiUpdates <- update
+}
+
+// waitForUI returns once every change queued before it has been applied
+func waitForUI() {
+	applied := make(chan bool)
+	updateUI(func() {
+		close(applied)
+	})
+	<-applied
+}
+
+// showInformation shows a dialog from any goroutine
+func showInformation(title string, message string, win fyne.Window) {
+	updateUI(func() {
+		dialog.ShowInformation(title, message, win)
+	})
+}
+
+// tableRows holds the rows shown by a table. The table reads them while they
+// are being replaced, so a row that has gone comes back as not ok.
+type tableRows[T any] struct {
+	sync.Mutex
+	rows []T
+}
+
+func (t *tableRows[T]) Set(rows []T) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.rows = rows
+}
+
+func (t *tableRows[T]) Len() int {
+	t.Lock()
+	defer t.Unlock()
+
+	return len(t.rows)
+}
+
+func (t *tableRows[T]) Row(i int) (row T, ok bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	if i < 0 || i >= len(t.rows) {
+		return row, false
+	}
+	return t.rows[i], true
+}
+
+// The number of stones the Details table regenerates at a time
+const stonePageSize = 256
+
+// stonePager serves the rows of the Details table from a trajectory,
+// regenerating a page of stones at a time as the table scrolls
+type stonePager struct {
+	sync.Mutex
+	trajectory *trajectory
+	pageStart  int
+	page       []*big.Int
+}
+
+func (p *stonePager) Set(t *trajectory) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.trajectory = t
+	p.page = nil
+}
+
+func (p *stonePager) Len() int {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.trajectory == nil {
+		return 0
+	}
+	return p.trajectory.Len()
+}
+
+func (p *stonePager) Row(i int) (row stoneRow, ok bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.trajectory == nil || i < 0 || i >= p.trajectory.Len() {
+		return row, false
+	}
+	if p.page == nil || i < p.pageStart || i >= p.pageStart+len(p.page) {
+		p.pageStart = i - i%stonePageSize
+		p.page = p.trajectory.Stones(p.pageStart, stonePageSize)
+	}
+	return stoneRow{stone: p.page[i-p.pageStart], upwards: p.trajectory.Upwards(i)}, true
+}
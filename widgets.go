@@ -2,34 +2,39 @@ package main
 
 import (
 	//"fyne.io/fyne/v2/data/validation"
-	"bytes"
+	"bufio"
 	"fmt"
+	"io"
+	"math"
 	"math/big"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/wcharczuk/go-chart/v2"
 	"github.com/wcharczuk/go-chart/v2/drawing"
 )
 
 type sequenceProgress struct {
-	stones         []float64
-	stonesRaw      []float64
-	stonesString   []string
-	upwards        []bool
+	trajectory     *trajectory
 	steps          int
+	stoppingTime   int
+	parityHash     uint64
 	upMoves        int
 	downMoves      int
-	maxStoneFloat  *big.Float
 	maxStoneInt    *big.Int
 	maxStoneString string
 	lastStone      bool
 	number         *big.Int
+	matched        bool // the value satisfies the predicate of the search it is part of
 }
 
 type collatzWorker struct {
@@ -41,15 +46,25 @@ var zeroBig = big.NewInt(0)
 var oneBig = big.NewInt(1)
 var twoBig = big.NewInt(2)
 var threeBig = big.NewInt(3)
-var highmaxStone = big.NewInt(0)
 
 // The channels for the UI
 var sequneceStatusChannel = make(chan sequenceProgress)
-var sequneceStatusPerfChannel = make(chan sequenceProgress, 100000)
-var pauseChannel = make(chan bool)
-var stopChannel = make(chan bool)
-var stepChannel = make(chan bool)
-var resumeChannel = make(chan bool)
+
+// runControl is what the Range tab buttons drive, either a session or a coordinator
+type runControl interface {
+	Pause()
+	Step()
+	Resume()
+	Stop()
+}
+
+// The ways the Range tab is given its values: every value between the limits,
+// a list from a file or a random sample between the limits
+var rangeModes = []string{"Range", "List", "Sample"}
+
+// The run in progress in the Range tab
+var rangeRun runControl
+var rangeRunLock sync.Mutex
 
 // The buttons for the UI
 var calcSingleBtn *widget.Button
@@ -58,6 +73,7 @@ var pauseBtn *widget.Button
 var stepBtn *widget.Button
 var resumeBtn *widget.Button
 var stopBtn *widget.Button
+var resumeRunBtn *widget.Button
 
 // UI elements for the stones
 var highwaterStoneLabel *widget.Label
@@ -73,33 +89,61 @@ var numDown *widget.Label
 var maxStone *widget.Label
 var seqLen *widget.Label
 
+// UI elements for the deviation from the heuristics
+var expectedSeqLen *widget.Label
+var expectedUpPercentage *widget.Label
+var oddDeviation *widget.Label
+var driftPerStep *widget.Label
+
 var detailStoneList *widget.Table
-var stoneStrings []string
-var upDirectionBool []bool
+var workersTable *widget.Table
+var coordinatorWorkers tableRows[remoteWorker]
+var stoneRows stonePager
+
+// stoneRow is a line of the Details table
+type stoneRow struct {
+	stone   *big.Int
+	upwards bool
+}
 
-var highwaterSteps int = 0
-var highwaterStepsNumber string
-var highwaterStone string
-var highwaterStoneNumber string
+// The base the Details table and Summary show values in, 0 until one is chosen
+var displayBase atomic.Int32
 
-var stepsSlice []float64
-var stepsNumberSlice []float64
+// The last value shown on the Single Value tab, only used on the UI goroutine
+var singleReport *sequenceProgress
 
 var progress *widget.ProgressBar
 var infProgress *widget.ProgressBarInfinite
 
-var workersDispatched int = 0
-var workersFinished int = 0
-
 var reportFreqencyInterval int = 1000
 
-// The objects holding the graphs
-var stonesChart *fyne.Container
-var stonesLogChart *fyne.Container
-var sequenceLengthChart *fyne.Container
+// The graphs
+var stonesChart *chartView
+var stonesLogChart *chartView
+var sequenceLengthChart *chartView
+
+// The entry base selects of the Single Value and Range tabs, which follow the default base
+var singleEntryBase *widget.Select
+var rangeEntryBase *widget.Select
+
+// The result tabs of the Single Value tab, so a chart can open the Details tab
+var singleStatusTabs *container.AppTabs
+var detailsTab *container.TabItem
+
+// workItem is a value for the worker pool and where its result should be sent.
+// The result of a search is tested against its predicate by the worker.
+type workItem struct {
+	value     big.Int
+	mapping   collatzMap
+	predicate *searchPredicate
+	results   chan sequenceProgress
+	done      *sync.WaitGroup
+}
+
+var workDistributorChannel = make(chan workItem)
 
-var workDistributorChannel = make(chan big.Int)
-var wg sync.WaitGroup
+// The number of workers in the pool shared by the Range tab and the API server
+var workerCount = 300
 
 func (w *collatzWorker) Start(workerID int) {
 
@@ -114,19 +158,48 @@ func (w *collatzWorker) Start(workerID int) {
 	go func() {
 		for {
 			select {
-			case value := <-workDistributorChannel:
+			case item := <-workDistributorChannel:
 				handled++
-				report := CollatzPerf(value)
-				sequneceStatusPerfChannel <- report
-				wg.Done()
+				report := CollatzPerf(item.value, item.mapping)
+				if item.predicate != nil {
+					report.matched = item.predicate.matches(&report)
+				}
+				valuesProcessed.Add(1)
+				item.results <- report
+				item.done.Done()
 			case <-w.finishedChannel:
 				//fmt.Printf("Process %d handled %d values\n", w.workerID, handled)
-				wg.Done()
+				return
 			}
 		}
 	}()
 }
 
+// startWorkerPool creates the workers that every range run shares
+func startWorkerPool(count int) {
+	for i := 0; i < count; i++ {
+		w := &collatzWorker{}
+		workersThreadSafeSlice.Push(w)
+		w.Start(i)
+	}
+}
+
+// resizeWorkerPool adds workers or stops the newest until the pool has count.
+// A worker that is stopped finishes the value it has first.
+func resizeWorkerPool(count int) {
+	for workersThreadSafeSlice.Len() < count {
+		w := &collatzWorker{}
+		w.Start(workersThreadSafeSlice.Len())
+		workersThreadSafeSlice.Push(w)
+	}
+	for workersThreadSafeSlice.Len() > count {
+		w := workersThreadSafeSlice.Pop()
+		go func() {
+			w.finishedChannel <- true
+		}()
+	}
+}
+
 type threadSafeSlice struct {
 	sync.Mutex
 	workers []*collatzWorker
@@ -139,6 +212,22 @@ func (slice *threadSafeSlice) Push(w *collatzWorker) {
 	slice.workers = append(slice.workers, w)
 }
 
+func (slice *threadSafeSlice) Pop() *collatzWorker {
+	slice.Lock()
+	defer slice.Unlock()
+
+	w := slice.workers[len(slice.workers)-1]
+	slice.workers = slice.workers[:len(slice.workers)-1]
+	return w
+}
+
+func (slice *threadSafeSlice) Len() int {
+	slice.Lock()
+	defer slice.Unlock()
+
+	return len(slice.workers)
+}
+
 func (slice *threadSafeSlice) Iter(routine func(*collatzWorker)) {
 	slice.Lock()
 	defer slice.Unlock()
@@ -155,27 +244,35 @@ func makeEntryTab(win fyne.Window) fyne.CanvasObject {
 	tabs := container.NewAppTabs(
 		container.NewTabItem("Single Value", makeSingleTab(win)),
 		container.NewTabItem("Range", makeMultiTab(win)),
+		container.NewTabItem("Database", makeDatabaseTab(win)),
+		container.NewTabItem("Coral", makeCoralTab(win)),
 	)
-	return container.NewBorder(widget.NewLabel("Collatz Conjecture Visualiser"), nil, nil, nil, tabs)
+	settingsBtn := widget.NewButtonWithIcon("Settings", theme.SettingsIcon(), func() {
+		updateUI(func() {
+			showSettingsDialog(win)
+		})
+	})
+	heading := container.NewBorder(nil, nil, nil, settingsBtn, widget.NewLabel("Collatz Conjecture Visualiser"))
+	return container.NewBorder(heading, nil, nil, nil, tabs)
 }
 
 func makeSingleTab(win fyne.Window) fyne.CanvasObject {
 
 	// The graph for the absolute values
-	stonesChart = container.NewStack()
+	stonesChart = newChartView(fyne.NewSize(200, 200))
+	stonesChart.OnTapped = showStoneDetails
 
 	// The graph for the log values
-	stonesLogChart = container.NewStack()
-
-	sequenceLengthChart = container.NewStack()
+	stonesLogChart = newChartView(fyne.NewSize(200, 200))
+	stonesLogChart.OnTapped = showStoneDetails
 
 	// The list of stones
 	detailStoneList = widget.NewTable(
 		func() (int, int) {
-			if len(stoneStrings) == 0 {
+			if stoneRows.Len() == 0 {
 				return 3, 3
 			}
-			return len(stoneStrings) + 1, 3
+			return stoneRows.Len() + 1, 3
 		},
 		func() fyne.CanvasObject {
 			item := widget.NewLabel("Template Wide Label")
@@ -202,17 +299,17 @@ func makeSingleTab(win fyne.Window) fyne.CanvasObject {
 				return
 			}
 
-			if len(stoneStrings) != 0 {
+			if row, ok := stoneRows.Row(i.Row - 1); ok {
 				if i.Col == 0 {
 					label.SetText(fmt.Sprintf("%d", i.Row))
 					return
 				}
 				if i.Col == 1 {
-					label.SetText(stoneStrings[i.Row-1])
+					label.SetText(abbreviate(formatValue(row.stone)))
 					return
 				}
 				if i.Col == 2 && i.Row > 1 {
-					if upDirectionBool[i.Row-1] {
+					if row.upwards {
 						label.SetText("Up")
 					} else {
 						label.SetText("Down")
@@ -242,6 +339,10 @@ func makeSingleTab(win fyne.Window) fyne.CanvasObject {
 	maxStone = widget.NewLabel("")
 	seqLen = widget.NewLabel("")
 	upDownPercentageLabel = widget.NewLabel("")
+	expectedSeqLen = widget.NewLabel("")
+	expectedUpPercentage = widget.NewLabel("")
+	oddDeviation = widget.NewLabel("")
+	driftPerStep = widget.NewLabel("")
 
 	summary := container.NewHBox(
 		container.NewVBox(
@@ -251,6 +352,10 @@ func makeSingleTab(win fyne.Window) fyne.CanvasObject {
 			widget.NewRichTextFromMarkdown("**Number of Upwards**"),
 			widget.NewRichTextFromMarkdown("**Number of Downwards**"),
 			widget.NewRichTextFromMarkdown("**Up/Down Percentage**"),
+			widget.NewRichTextFromMarkdown("**Expected Sequence Length**"),
+			widget.NewRichTextFromMarkdown("**Expected Up Percentage**"),
+			widget.NewRichTextFromMarkdown("**Odd Step Deviation**"),
+			widget.NewRichTextFromMarkdown("**Drift per Step**"),
 		),
 		container.NewVBox(
 			number,
@@ -259,58 +364,94 @@ func makeSingleTab(win fyne.Window) fyne.CanvasObject {
 			numUp,
 			numDown,
 			upDownPercentageLabel,
+			expectedSeqLen,
+			expectedUpPercentage,
+			oddDeviation,
+			driftPerStep,
 		),
 	)
 
 	// Put the elements into a tab set
-	statusTabs := container.NewAppTabs(
+	detailsTab = container.NewTabItem("Details", tableLayout)
+	singleStatusTabs = container.NewAppTabs(
 		container.NewTabItem("Summary", summary),
 		container.NewTabItem("Absolute Hailstone Chart", stonesChart),
 		container.NewTabItem("Log Hailstone Chart", stonesLogChart),
-		container.NewTabItem("Details", tableLayout),
+		detailsTab,
 	)
 
 	//create the left pane and put the two together into a split
 	splitCanvas := container.NewHSplit(
 		makeLeftPaneSingle(win),
-		container.NewBorder(nil, nil, nil, nil, statusTabs),
+		container.NewBorder(nil, nil, nil, nil, singleStatusTabs),
 	)
 	splitCanvas.Offset = 0.4
+	rememberSplit("split.single", splitCanvas)
 
 	return splitCanvas
 }
 func makeLeftPaneSingle(win fyne.Window) fyne.CanvasObject {
 
 	var entryValue *widget.Entry
+	prefs := fyne.CurrentApp().Preferences()
 
-	entryBase := widget.NewSelect([]string{"Base 2", "Base 10", "Base 16", "Base 36"}, func(string) {})
-	entryBase.SetSelected("Base 10")
+	entryBase := widget.NewSelect(entryBases(true), func(string) {})
+	entryBase.SetSelected(lastEntryBase(lastSingleBaseKey))
 	entryBase.PlaceHolder = "Select a base"
 	entryBase.OnChanged = func(s string) {
-		checkValidation(entryValue.Text, s, win)
+		entryValue.Validate()
+	}
+	singleEntryBase = entryBase
+
+	// The display base only changes how the values are written, so the last value is shown again
+	displayBaseSelect := widget.NewSelect(entryBases(false), func(string) {})
+	displayBaseSelect.SetSelected("Base 10")
+	displayBaseSelect.OnChanged = func(s string) {
+		displayBase.Store(int32(parseEntryBase(s)))
+		updateUI(func() {
+			if singleReport != nil {
+				showSingleSummary(singleReport)
+			}
+			detailStoneList.Refresh()
+		})
 	}
 	entryValue = widget.NewEntry()
-	entryValue.SetPlaceHolder("")
+	entryValue.SetPlaceHolder("2^100+1")
+	entryValue.Validator = expressionValidator(entryBase)
 	entryValue.OnChanged = func(s string) {
 		s = removeSpaces(s)
-		checkValidation(s, entryBase.Selected, win)
 		entryValue.SetText(s)
 	}
+	entryValue.SetText(prefs.String(lastValueKey))
 
 	calcSingleBtn = widget.NewButton("Calculate", func() {
+		rememberEntries(map[string]string{lastSingleBaseKey: entryBase.Selected, lastValueKey: entryValue.Text})
 		go calcStones(entryValue.Text, entryBase.Selected, win)
 	})
 	calcSingleBtn.Enable()
 
+	// The report is read where it is set, on the update goroutine
+	soundBtn := widget.NewButton("Export Sound", func() {
+		updateUI(func() {
+			showSoundDialog(singleReport, win)
+		})
+	})
+	animationBtn := widget.NewButton("Export Animation", func() {
+		updateUI(func() {
+			showAnimationDialog(singleReport, win)
+		})
+	})
+
 	return container.NewBorder(container.NewVBox(
 		widget.NewForm(
 			widget.NewFormItem(fmt.Sprintf("%15s", "Entry Base:"), entryBase),
 			widget.NewFormItem(fmt.Sprintf("%15s", "Value:"), entryValue),
-		)), calcSingleBtn, nil, nil, nil)
+			widget.NewFormItem(fmt.Sprintf("%15s", "Display Base:"), displayBaseSelect),
+		)), container.NewVBox(calcSingleBtn, container.NewGridWithColumns(2, soundBtn, animationBtn)), nil, nil, makeHistoryPanel(entryBase, entryValue, win))
 }
 func makeMultiTab(win fyne.Window) fyne.CanvasObject {
 
-	sequenceLengthChart = container.NewStack()
+	sequenceLengthChart = newChartView(fyne.NewSize(400, 400))
 
 	// The summary tab
 
@@ -333,10 +474,50 @@ func makeMultiTab(win fyne.Window) fyne.CanvasObject {
 		),
 	)
 
+	// The workers connected when the tab is coordinating a distributed sweep
+	workerHeadings := []string{"Worker", "Blocks", "Values", "Leases", "Last Seen"}
+	workersTable = widget.NewTable(
+		func() (int, int) {
+			return coordinatorWorkers.Len() + 1, len(workerHeadings)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("Template Wide Label")
+		},
+		func(i widget.TableCellID, cell fyne.CanvasObject) {
+			label := cell.(*widget.Label)
+			if i.Row == 0 {
+				label.SetText(workerHeadings[i.Col])
+				return
+			}
+			w, ok := coordinatorWorkers.Row(i.Row - 1)
+			if !ok {
+				label.SetText("")
+				return
+			}
+			switch i.Col {
+			case 0:
+				label.SetText(w.Name)
+			case 1:
+				label.SetText(fmt.Sprintf("%d", w.Blocks))
+			case 2:
+				label.SetText(fmt.Sprintf("%d", w.Values))
+			case 3:
+				label.SetText(fmt.Sprintf("%d", w.Leased))
+			case 4:
+				label.SetText(w.LastSeen.Format("15:04:05"))
+			}
+		})
+	workersTable.StickyRowCount = 1
+	workersTable.SetColumnWidth(0, 200)
+
 	// Put the elements into a tab set
 	statusTabs := container.NewAppTabs(
 		container.NewTabItem("High Water Marks", summary),
 		container.NewTabItem("Sequence Length Chart", sequenceLengthChart),
+		container.NewTabItem("Heatmap", makeHeatmapTab(win)),
+		container.NewTabItem("Statistics", makeStatisticsTab()),
+		container.NewTabItem("Matches", makeMatchesTab()),
+		container.NewTabItem("Workers", workersTable),
 	)
 
 	// Put the tab set into a border layout at the bottop
@@ -345,6 +526,143 @@ func makeMultiTab(win fyne.Window) fyne.CanvasObject {
 	//create the left pane and put the two together into a split
 	splitCanvas := container.NewHSplit(makeLeftPaneMulti(win), border)
 	splitCanvas.Offset = 0.4
+	rememberSplit("split.range", splitCanvas)
+
+	return splitCanvas
+}
+func makeDatabaseTab(win fyne.Window) fyne.CanvasObject {
+
+	var entries tableRows[storeEntry]
+
+	entryValue := widget.NewEntry()
+	entryMinSteps := widget.NewEntry()
+	entryMaxSteps := widget.NewEntry()
+	entryMinStopping := widget.NewEntry()
+	entryMaxStopping := widget.NewEntry()
+	entryParity := widget.NewEntry()
+	entryLimit := widget.NewEntry()
+	entryLimit.SetText("1000")
+	countLabel := widget.NewLabel("")
+
+	headings := []string{"Number", "Steps", "Stopping Time", "Max Stone", "Parity Hash"}
+	resultTable := widget.NewTable(
+		func() (int, int) {
+			return entries.Len() + 1, len(headings)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("Template Wide Label")
+		},
+		func(i widget.TableCellID, cell fyne.CanvasObject) {
+			label := cell.(*widget.Label)
+			if i.Row == 0 {
+				label.SetText(headings[i.Col])
+				return
+			}
+			e, ok := entries.Row(i.Row - 1)
+			if !ok {
+				label.SetText("")
+				return
+			}
+			switch i.Col {
+			case 0:
+				label.SetText(e.Number)
+			case 1:
+				label.SetText(fmt.Sprintf("%d", e.Steps))
+			case 2:
+				label.SetText(fmt.Sprintf("%d", e.StoppingTime))
+			case 3:
+				label.SetText(e.MaxStone)
+			case 4:
+				label.SetText(e.ParityHash)
+			}
+		})
+	resultTable.StickyRowCount = 1
+	for col, width := range []float32{200, 80, 120, 300, 160} {
+		resultTable.SetColumnWidth(col, width)
+	}
+
+	// intValue reads a count from an entry, 0 when it is empty, and is not ok
+	// once a dialog has said why the entry is not valid
+	intValue := func(entry *widget.Entry) (int, bool) {
+		if entry.Text == "" {
+			return 0, true
+		}
+		v, ok := checkValidation(removeSpaces(entry.Text), "Base 10", win)
+		if !ok {
+			return 0, false
+		}
+		if v.BitLen() > 31 {
+			showInformation("Number Format Error", fmt.Sprintf("The entry %s is too large", entry.Text), win)
+			return 0, false
+		}
+		return int(v.Int64()), true
+	}
+
+	searchBtn := widget.NewButton("Search", func() {
+		if results == nil {
+			showInformation("Database Error", "The results database is not available", win)
+			return
+		}
+
+		// A value on its own is looked up directly in the index
+		var found []storeEntry
+		if entryValue.Text != "" {
+			n, ok := checkValidation(removeSpaces(entryValue.Text), "Base 10", win)
+			if !ok {
+				return
+			}
+			if e, ok := results.Get(&n); ok {
+				found = append(found, e)
+			}
+		} else {
+			// The query is not run with the filter of an entry that is not valid left out
+			query := storeQuery{ParityHash: strings.TrimSpace(entryParity.Text)}
+			for _, field := range []struct {
+				value *int
+				entry *widget.Entry
+			}{
+				{&query.MinSteps, entryMinSteps},
+				{&query.MaxSteps, entryMaxSteps},
+				{&query.MinStoppingTime, entryMinStopping},
+				{&query.MaxStoppingTime, entryMaxStopping},
+				{&query.Limit, entryLimit},
+			} {
+				v, ok := intValue(field.entry)
+				if !ok {
+					return
+				}
+				*field.value = v
+			}
+			var err error
+			found, err = results.Query(query)
+			if err != nil {
+				showInformation("Database Error", err.Error(), win)
+			}
+		}
+		entries.Set(found)
+		count := fmt.Sprintf("%d of %d stored values", len(found), results.Len())
+		updateUI(func() {
+			countLabel.SetText(count)
+			resultTable.Refresh()
+		})
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem(fmt.Sprintf("%15s", "Value:"), entryValue),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Min Steps:"), entryMinSteps),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Max Steps:"), entryMaxSteps),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Min Stopping Time:"), entryMinStopping),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Max Stopping Time:"), entryMaxStopping),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Parity Hash:"), entryParity),
+		widget.NewFormItem(fmt.Sprintf("%15s", "Limit:"), entryLimit),
+	)
+
+	splitCanvas := container.NewHSplit(
+		container.NewBorder(form, searchBtn, nil, nil, nil),
+		container.NewBorder(countLabel, nil, nil, nil, resultTable),
+	)
+	splitCanvas.Offset = 0.4
+	rememberSplit("split.database", splitCanvas)
 
 	return splitCanvas
 }
@@ -353,49 +671,171 @@ func makeLeftPaneMulti(win fyne.Window) fyne.CanvasObject {
 	var entryLower *widget.Entry
 	var entryUpper *widget.Entry
 	var reportFreq *widget.Entry
+	prefs := fyne.CurrentApp().Preferences()
 
-	entryBase := widget.NewSelect([]string{"Base 2", "Base 10", "Base 16", "Base 36"}, func(string) {})
-	entryBase.SetSelected("Base 10")
+	entryBase := widget.NewSelect(entryBases(true), func(string) {})
+	entryBase.SetSelected(lastEntryBase(lastRangeBaseKey))
 	entryBase.PlaceHolder = "Select a base"
 	entryBase.OnChanged = func(s string) {
-		checkValidation(entryLower.Text, s, win)
+		entryLower.Validate()
+		entryUpper.Validate()
 	}
+	rangeEntryBase = entryBase
 
 	entryLower = widget.NewEntry()
 	entryLower.SetPlaceHolder("")
+	entryLower.Validator = expressionValidator(entryBase)
 	entryLower.OnChanged = func(s string) {
 		s = removeSpaces(s)
-		checkValidation(s, entryBase.Selected, win)
 		entryLower.SetText(s)
 	}
 	entryUpper = widget.NewEntry()
 	entryUpper.SetPlaceHolder("")
+	entryUpper.Validator = expressionValidator(entryBase)
 	entryUpper.OnChanged = func(s string) {
 		s = removeSpaces(s)
-		checkValidation(s, entryBase.Selected, win)
 		entryUpper.SetText(s)
 	}
+	entryLower.SetText(prefs.String(lastLowerKey))
+	entryUpper.SetText(prefs.String(lastUpperKey))
+
+	// A stride and residue run through the values n ≡ residue (mod stride) between the limits
+	entryStride := widget.NewEntry()
+	entryStride.SetPlaceHolder("1")
+	entryStride.Validator = expressionValidator(entryBase)
+	entryStride.OnChanged = func(s string) {
+		entryStride.SetText(removeSpaces(s))
+	}
+	entryResidue := widget.NewEntry()
+	entryResidue.SetPlaceHolder("Lower Limit")
+	entryResidue.Validator = residueValidator(entryBase)
+	entryResidue.OnChanged = func(s string) {
+		entryResidue.SetText(removeSpaces(s))
+	}
+	entryStride.SetText(prefs.String(lastStrideKey))
+	entryResidue.SetText(prefs.String(lastResidueKey))
+
+	// A list run takes its values from a file, one a line, instead of between the limits
+	var listValues []*big.Int
+	listLabel := widget.NewLabel("No list loaded")
+	loadListBtn := widget.NewButton("Load List", func() {
+		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+			if err != nil || r == nil {
+				return
+			}
+			defer r.Close()
+			values, err := readValueList(r, entryBase.Selected)
+			if err != nil {
+				showInformation("List Error", fmt.Sprintf("%s could not be read: %v", r.URI().Name(), err), win)
+				return
+			}
+			listValues = values
+			text := fmt.Sprintf("%d values from %s", len(values), r.URI().Name())
+			updateUI(func() {
+				listLabel.SetText(text)
+			})
+		}, win)
+	})
+	// A sample run draws values at random between the limits, the same values for the same seed
+	entrySampleSize := widget.NewEntry()
+	entrySampleSize.SetText("1000")
+	entrySeed := widget.NewEntry()
+	entrySeed.SetPlaceHolder("1")
+
+	// A search keeps the values whose results satisfy a predicate, in any mode
+	entrySearch := widget.NewEntry()
+	entrySearch.SetPlaceHolder("steps == 500")
+	entrySearch.Validator = func(s string) error {
+		if s == "" {
+			return nil
+		}
+		_, err := parsePredicate(s)
+		return err
+	}
+	entryStopAfter := widget.NewEntry()
+	entryStopAfter.SetPlaceHolder("All matches")
+	entrySearch.SetText(prefs.String(lastSearchKey))
+	entryStopAfter.SetText(prefs.String(lastStopAfterKey))
+
+	// Each entry is enabled in the modes that use it
+	modeWidgets := map[fyne.Disableable][]string{
+		entryLower:      {rangeModes[0], rangeModes[2]},
+		entryUpper:      {rangeModes[0], rangeModes[2]},
+		entryStride:     {rangeModes[0]},
+		entryResidue:    {rangeModes[0]},
+		loadListBtn:     {rangeModes[1]},
+		entrySampleSize: {rangeModes[2]},
+		entrySeed:       {rangeModes[2]},
+	}
+	valuesRadio := widget.NewRadioGroup(rangeModes, func(mode string) {
+		for w, modes := range modeWidgets {
+			if oneOf(mode, modes, "") != "" {
+				w.Enable()
+			} else {
+				w.Disable()
+			}
+		}
+	})
+	valuesRadio.Horizontal = true
+	valuesRadio.Required = true
+	valuesRadio.SetSelected(rangeModes[0])
 
 	reportFreq = widget.NewEntry()
 	reportFreq.SetPlaceHolder("1000")
 	reportFreq.OnChanged = func(s string) {
 		s = removeSpaces(s)
-		v, ok := checkValidation(s, "10", win)
+		v, ok := checkValidation(s, "Base 10", win)
 		reportFreq.SetText(s)
 		if ok {
 			reportFreqencyInterval = int(v.Int64())
+			prefs.SetInt(reportFrequencyKey, reportFreqencyInterval)
 		}
 	}
+	if f := prefs.Int(reportFrequencyKey); f > 0 {
+		reportFreq.SetText(strconv.Itoa(f))
+	}
+
+	// Coordinating hands the range out to remote workers instead of the local pool alone
+	entryListen := widget.NewEntry()
+	entryListen.SetText(":7070")
+	entryListen.Disable()
+	entryBlock := widget.NewEntry()
+	entryBlock.SetText(fmt.Sprintf("%d", defaultBlockSize))
+	entryBlock.Disable()
+	coordinateCheck := widget.NewCheck("Coordinate remote workers", func(on bool) {
+		if on {
+			entryListen.Enable()
+			entryBlock.Enable()
+		} else {
+			entryListen.Disable()
+			entryBlock.Disable()
+		}
+	})
 
 	fixed := container.NewVBox(
 		widget.NewForm(
 			widget.NewFormItem(fmt.Sprintf("%15s", "Entry Base:"), entryBase),
+			widget.NewFormItem(fmt.Sprintf("%15s", "Values:"), valuesRadio),
 			widget.NewFormItem(fmt.Sprintf("%15s", "Lower Limit:"), entryLower),
 			widget.NewFormItem(fmt.Sprintf("%15s", "Upper Limit:"), entryUpper),
+			widget.NewFormItem(fmt.Sprintf("%15s", "Stride:"), entryStride),
+			widget.NewFormItem(fmt.Sprintf("%15s", "Residue:"), entryResidue),
+			widget.NewFormItem(fmt.Sprintf("%15s", "List:"), container.NewBorder(nil, nil, nil, loadListBtn, listLabel)),
+			widget.NewFormItem(fmt.Sprintf("%15s", "Sample Size:"), entrySampleSize),
+			widget.NewFormItem(fmt.Sprintf("%15s", "Seed:"), entrySeed),
+			widget.NewFormItem(fmt.Sprintf("%15s", "Search:"), entrySearch),
+			widget.NewFormItem(fmt.Sprintf("%15s", "Stop After:"), entryStopAfter),
 			widget.NewFormItem(fmt.Sprintf("%15s", "Report Frequency:"), reportFreq),
+		),
+		coordinateCheck,
+		widget.NewForm(
+			widget.NewFormItem(fmt.Sprintf("%15s", "Listen Address:"), entryListen),
+			widget.NewFormItem(fmt.Sprintf("%15s", "Block Size:"), entryBlock),
 		))
 
 	progress = widget.NewProgressBar()
+	progress.Min = 0
+	progress.Max = 100
 	progress.Resize(fyne.NewSize(200, 20))
 	progress.Hide()
 
@@ -404,282 +844,806 @@ func makeLeftPaneMulti(win fyne.Window) fyne.CanvasObject {
 
 	entryLayout := container.NewVBox(fixed, progress)
 
+	// The entries are read here, on the UI goroutine, and the run is handed the values
+	startRun := func() {
+		rememberEntries(map[string]string{lastRangeBaseKey: entryBase.Selected, lastLowerKey: entryLower.Text, lastUpperKey: entryUpper.Text, lastStrideKey: entryStride.Text, lastResidueKey: entryResidue.Text,
+			lastSearchKey: entrySearch.Text, lastStopAfterKey: entryStopAfter.Text})
+		mode := valuesRadio.Selected
+		if coordinateCheck.Checked {
+			// Remote workers are handed blocks of consecutive values
+			if mode != rangeModes[0] || (entryStride.Text != "" && entryStride.Text != "1") || entryResidue.Text != "" || entrySearch.Text != "" {
+				showInformation("Coordinator Error", "Remote workers can only run a range without a stride or a search", win)
+				finishSweep()
+				return
+			}
+			go calcStonesCoordinated(entryLower.Text, entryUpper.Text, entryBase.Selected, entryListen.Text, entryBlock.Text, win)
+			return
+		}
+		search, ok := parseRangeSearch(strings.TrimSpace(entrySearch.Text), removeSpaces(entryStopAfter.Text), win)
+		if !ok {
+			finishSweep()
+			return
+		}
+		switch mode {
+		case rangeModes[1]:
+			go calcStonesList(listValues, reportFreqencyInterval, search, win)
+			return
+		case rangeModes[2]:
+			go calcStonesSample(entryLower.Text, entryUpper.Text, removeSpaces(entrySampleSize.Text), removeSpaces(entrySeed.Text), entryBase.Selected, reportFreqencyInterval, search, win)
+			return
+		}
+		go calcStonesMulti(entryLower.Text, entryUpper.Text, entryStride.Text, entryResidue.Text, entryBase.Selected, reportFreqencyInterval, search, win)
+	}
+
+	// A run replaces the checkpoint, so an unfinished run is only given up when the user agrees
 	calcFunc := func() {
-		calcStonesMulti(entryLower.Text, entryUpper.Text, entryBase.Selected, win)
+		cp, err := loadCheckpoint()
+		if err != nil || cp.Finished || coordinateCheck.Checked {
+			startRun()
+			return
+		}
+		message := fmt.Sprintf("The previous run %s has not completed. A new run replaces its checkpoint, so it can no longer be resumed.", cp.describe())
+		dialog.ShowConfirm("Replace Unfinished Run", message, func(replace bool) {
+			if !replace {
+				finishSweep()
+				return
+			}
+			startRun()
+		}, win)
 	}
 
-	navCanvas := container.NewBorder(entryLayout, makeMultiButtons(calcFunc), nil, nil, nil)
+	resumeFunc := func() {
+		cp, err := loadCheckpoint()
+		if err != nil {
+			showInformation("Resume Error", fmt.Sprintf("No previous run could be loaded: %v", err), win)
+			finishSweep()
+			return
+		}
+		if cp.Finished {
+			showInformation("Resume", fmt.Sprintf("The previous run %s has already completed", cp.describe()), win)
+			finishSweep()
+			return
+		}
+		entryBase.SetSelected("Base 10")
+		if cp.SampleSize > 0 {
+			valuesRadio.SetSelected(rangeModes[2])
+			entryLower.SetText(cp.Lower)
+			entryUpper.SetText(cp.lastValue())
+			entrySampleSize.SetText(strconv.FormatInt(cp.SampleSize, 10))
+			entrySeed.SetText(strconv.FormatInt(cp.Seed, 10))
+		} else if cp.Values != nil {
+			valuesRadio.SetSelected(rangeModes[1])
+			listLabel.SetText(fmt.Sprintf("%d values from the previous run", len(cp.Values)))
+		} else {
+			valuesRadio.SetSelected(rangeModes[0])
+			entryLower.SetText(cp.Lower)
+			entryUpper.SetText(cp.lastValue())
+			entryStride.SetText(cp.Stride)
+			entryResidue.SetText("")
+		}
+		entrySearch.SetText(cp.Search)
+		entryStopAfter.SetText("")
+		if cp.SearchLimit > 0 {
+			entryStopAfter.SetText(strconv.Itoa(cp.SearchLimit))
+		}
+		go resumeStonesMulti(cp, reportFreqencyInterval, win)
+	}
+
+	navCanvas := container.NewBorder(entryLayout, makeMultiButtons(calcFunc, resumeFunc), nil, nil, nil)
 
 	return navCanvas
 }
-func makeMultiButtons(calcFunc func()) fyne.CanvasObject {
+func makeMultiButtons(calcFunc func(), resumeFunc func()) fyne.CanvasObject {
 	calcBtn = widget.NewButton("Calculate", func() {
-		calcBtn.Disable()
-		pauseBtn.Enable()
-		stepBtn.Enable()
-		stopBtn.Enable()
-		go calcFunc()
+		startSweep()
+		calcFunc()
+	})
+	resumeRunBtn = widget.NewButton("Resume previous run", func() {
+		startSweep()
+		resumeFunc()
 	})
 	pauseBtn = widget.NewButton("Pause", func() {
-		pauseBtn.Disable()
-		resumeBtn.Enable()
-		pauseChannel <- true
+		pausedButtons(true)
+		if run := currentRangeRun(); run != nil {
+			run.Pause()
+		}
 	})
 	stepBtn = widget.NewButton("Step", func() {
-		pauseBtn.Disable()
-		resumeBtn.Enable()
-		stepChannel <- true
+		pausedButtons(true)
+		if run := currentRangeRun(); run != nil {
+			run.Step()
+		}
 	})
 	resumeBtn = widget.NewButton("Resume", func() {
-		pauseBtn.Enable()
-		resumeBtn.Disable()
-		resumeChannel <- true
+		pausedButtons(false)
+		if run := currentRangeRun(); run != nil {
+			run.Resume()
+		}
 	})
 	stopBtn = widget.NewButton("Stop", func() {
-		stopChannel <- true
-		calcBtn.Enable()
+		updateUI(func() {
+			stopBtn.Disable()
+		})
+		if run := currentRangeRun(); run != nil {
+			run.Stop()
+		}
 	})
-	buttonLayout := container.NewGridWithColumns(2, calcBtn, pauseBtn, stepBtn, resumeBtn, stopBtn)
+	buttonLayout := container.NewGridWithColumns(2, calcBtn, pauseBtn, stepBtn, resumeBtn, stopBtn, resumeRunBtn)
 
 	pauseBtn.Disable()
 	stepBtn.Disable()
 	resumeBtn.Disable()
 	stopBtn.Disable()
 	calcBtn.Enable()
+	resumeRunBtn.Enable()
 
 	return buttonLayout
 }
-func maintainHighwaterMarks(sequenceReport sequenceProgress) {
+func setRangeRun(run runControl) {
+	rangeRunLock.Lock()
+	defer rangeRunLock.Unlock()
 
-	if sequenceReport.steps > highwaterSteps {
-		highwaterSteps = sequenceReport.steps
-		highwaterStepsNumber = sequenceReport.number.String()
-	}
+	rangeRun = run
+}
+func currentRangeRun() runControl {
+	rangeRunLock.Lock()
+	defer rangeRunLock.Unlock()
 
-	//compare n to the highwaterstone and update if n is greater
-	if sequenceReport.maxStoneInt.Cmp(highmaxStone) == 1 {
-		highmaxStone = new(big.Int).Set(sequenceReport.maxStoneInt)
-		highwaterStone = highmaxStone.String()
-		highwaterStoneNumber = sequenceReport.number.String()
-	}
+	return rangeRun
+}
+
+// currentRangeSession returns the session of the Range tab, if it is running one
+func currentRangeSession() *RunSession {
+	session, _ := currentRangeRun().(*RunSession)
+	return session
 }
 func handleSingleModeStatusReport() {
 
 	for sequenceReport := range sequneceStatusChannel {
 
-		stepsSlice = append(stepsSlice, float64(sequenceReport.steps))
+		stoneRows.Set(sequenceReport.trajectory)
 
-		sf, _ := bigIntToFloat64(sequenceReport.number)
-		stepsNumberSlice = append(stepsNumberSlice, sf)
-
-		stoneStrings = sequenceReport.stonesString
-		upDirectionBool = sequenceReport.upwards
-
-		number.SetText(sequenceReport.stonesString[0])
-		upDownPercentage := float64(sequenceReport.upMoves) / float64(sequenceReport.upMoves+sequenceReport.downMoves) * 100
-		upDownPercentageLabel.SetText(fmt.Sprintf("%.2f%%", upDownPercentage))
-
-		seqLen.SetText(fmt.Sprintf("%d", len(sequenceReport.stonesString)-1))
-		maxStone.SetText(sequenceReport.maxStoneFloat.String())
-		numUp.SetText(fmt.Sprintf("%d", sequenceReport.upMoves))
-		numDown.SetText(fmt.Sprintf("%d", sequenceReport.downMoves))
-
-		xV := make([]float64, len(sequenceReport.stonesRaw))
-		for idx := 0; idx < len(sequenceReport.stonesRaw); idx++ {
-			xV[idx] = float64(idx)
-		}
+		report := sequenceReport
+		updateUI(func() {
+			singleReport = &report
+			showSingleSummary(&report)
+		})
 
-		refreshLogChart(&xV, &sequenceReport)
-		refreshAbsoluteChart(&xV, &sequenceReport)
+		shift := absoluteShift(sequenceReport.maxStoneInt)
+		stonesLogChart.SetSeries(&hailstoneSeries{trajectory: sequenceReport.trajectory, log: true})
+		stonesChart.SetSeries(&hailstoneSeries{trajectory: sequenceReport.trajectory, shift: shift})
 
-		detailStoneList.Resize(fyne.NewSize(500, 400))
-		detailStoneList.Refresh()
+		updateUI(func() {
+			detailStoneList.Resize(fyne.NewSize(500, 400))
+			detailStoneList.Refresh()
+		})
 	}
 }
-func handleMultiModeStatusReport() {
-	for sequenceReport := range sequneceStatusPerfChannel {
 
-		workersFinished++
-		maintainHighwaterMarks(sequenceReport)
-		stepsSlice = append(stepsSlice, float64(sequenceReport.steps))
-		sf, _ := bigIntToFloat64(sequenceReport.number)
-		stepsNumberSlice = append(stepsNumberSlice, sf)
+// showSingleSummary fills in the Summary tab in the display base
+func showSingleSummary(report *sequenceProgress) {
+	upDownPercentage := float64(report.upMoves) / float64(report.upMoves+report.downMoves) * 100
+
+	number.SetText(abbreviate(formatValue(report.number)))
+	upDownPercentageLabel.SetText(fmt.Sprintf("%.2f%%", upDownPercentage))
+	seqLen.SetText(fmt.Sprintf("%d", report.steps))
+	maxStone.SetText(abbreviate(formatValue(report.maxStoneInt)))
+	numUp.SetText(fmt.Sprintf("%d", report.upMoves))
+	numDown.SetText(fmt.Sprintf("%d", report.downMoves))
+	showSingleDeviation(report)
+}
 
-		if workersFinished%reportFreqencyInterval == 0 || workersFinished == workersDispatched {
-			highwaterStepsLabel.SetText(fmt.Sprintf("%d", highwaterSteps))
-			highwaterStepsNumberLabel.SetText(highwaterStepsNumber)
-			highwaterStoneLabel.SetText(highwaterStone)
-			highwaterStoneNumberLabel.SetText(highwaterStoneNumber)
-			percentFinished := float64(workersFinished) / float64(workersDispatched) * 100
-			progress.SetValue(percentFinished)
+// showSingleDeviation fills in how far a finished trajectory strays from the heuristics
+func showSingleDeviation(report *sequenceProgress) {
+	if !report.lastStone {
+		for _, l := range []*widget.Label{expectedSeqLen, expectedUpPercentage, oddDeviation, driftPerStep} {
+			l.SetText("")
 		}
-
+		return
 	}
-}
 
+	d := newTrajectoryDeviation(report)
+	expectedSeqLen.SetText(fmt.Sprintf("%.1f, %+.1f observed", d.expectedSteps, d.stepsDeviation))
+	expectedUpPercentage.SetText(fmt.Sprintf("%.2f%%, %.2f%% observed", d.expectedOddShare*100, d.oddShare*100))
+	oddDeviation.SetText(fmt.Sprintf("%+.2fσ", d.oddSigma))
+	driftPerStep.SetText(fmt.Sprintf("%.4f bits, %.4f expected", d.drift, d.expectedDrift))
+}
 func calcStones(value string, base string, win fyne.Window) {
 
-	number.SetText("")
-	upDownPercentageLabel.SetText("")
-	seqLen.SetText("")
-	maxStone.SetText("")
-	numUp.SetText("")
-	numDown.SetText("")
+	updateUI(func() {
+		number.SetText("")
+		upDownPercentageLabel.SetText("")
+		seqLen.SetText("")
+		maxStone.SetText("")
+		numUp.SetText("")
+		numDown.SetText("")
+		showSingleDeviation(&sequenceProgress{})
+	})
 	clearCharts()
 
 	nv, ok := checkValidation(value, base, win)
 	if !ok {
 		return
 	}
-	rep := Collatz(nv, nil, 100)
-	calcSingleBtn.Enable()
+
+	// Show the summary straight away if the value has been computed before.
+	// The database only holds the standard map.
+	mapping := currentSettings().Map
+	if results != nil && mapping == standardMap {
+		if e, ok := results.Get(&nv); ok {
+			updateUI(func() {
+				number.SetText(formatDecimal(e.Number))
+				seqLen.SetText(fmt.Sprintf("%d", e.Steps))
+				maxStone.SetText(formatDecimal(e.MaxStone))
+			})
+		}
+	}
+
+	rep := Collatz(nv, mapping, nil, 100)
+	updateUI(func() {
+		calcSingleBtn.Enable()
+	})
+
+	if results != nil && mapping == standardMap {
+		if err := results.Put(newStoreEntry(rep)); err != nil {
+			showInformation("Database Error", fmt.Sprintf("Unable to store the result: %v", err), win)
+		}
+	}
+
+	if singleHistory != nil {
+		singleHistory.Add(historyEntry{
+			Input:    value,
+			Base:     base,
+			Number:   rep.number.String(),
+			Steps:    rep.steps,
+			MaxStone: rep.maxStoneInt.String(),
+			Time:     time.Now(),
+		})
+		showHistory()
+	}
 
 	if sequneceStatusChannel != nil {
 		sequneceStatusChannel <- rep
 	}
 }
-func calcStonesMulti(lower string, upper string, base string, win fyne.Window) {
 
-	highwaterSteps = 0
-	highwaterStepsNumber = ""
-	highwaterStone = ""
-	highwaterStoneNumber = ""
-	highmaxStone = big.NewInt(0)
-	progress.SetValue(0)
-	clearCharts()
-	progress.Show()
+// calcStonesMulti runs the values from lower to upper, both included. With a
+// stride only the values n ≡ residue (mod stride) are run, from lower if no
+// residue is given. The residue is from 0 to stride-1.
+func calcStonesMulti(lower string, upper string, stride string, residue string, base string, reportFrequency int, search rangeSearch, win fyne.Window) {
 
 	nl, ok := checkValidation(lower, base, win)
 	if !ok {
+		finishSweep()
 		return
 	}
 
 	nu, ok := checkValidation(upper, base, win)
 	if !ok {
+		finishSweep()
 		return
 	}
 
 	if nu.Cmp(&nl) == -1 {
-		dialog.ShowInformation("Number Format Error", "Upper limit is smaller than the lower limit", win)
+		showInformation("Number Format Error", "Upper limit is smaller than the lower limit", win)
+		finishSweep()
 		return
 	}
 
-	// Create the worker threads
-	for i := 0; i < 300; i++ {
-		w := &collatzWorker{}
-		workersThreadSafeSlice.Push(w)
-		w.Start(i)
+	ns := *big.NewInt(1)
+	if stride != "" {
+		if ns, ok = checkValidation(stride, base, win); !ok {
+			finishSweep()
+			return
+		}
 	}
 
-	workersDispatched = 0
-	workersFinished = 0
+	// The first value is the first from the lower limit with the residue
+	first := new(big.Int).Set(&nl)
+	if residue != "" {
+		nr, err := evalNonNegative(residue, parseEntryBase(base))
+		if err != nil {
+			showInformation("Number Format Error", fmt.Sprintf("The entry %s is not a valid input for %s: %v", residue, base, err), win)
+			finishSweep()
+			return
+		}
+		if nr.Cmp(&ns) != -1 {
+			showInformation("Number Format Error", fmt.Sprintf("The residue %s is not less than the stride %s", residue, stride), win)
+			finishSweep()
+			return
+		}
+		gap := new(big.Int).Sub(nr, &nl)
+		first.Add(first, gap.Mod(gap, &ns))
+	}
 
-	progress.Min = 0
-	progress.Max = 100
-	// Distribute the work
-	for nl.Cmp(&nu) == -1 {
-		wg.Add(1)
-		n := new(big.Int).Set(&nl)
+	tracker, err := newStrideTracker(first, new(big.Int).Add(&nu, oneBig), &ns)
+	if err != nil {
+		showInformation("Range Error", err.Error(), win)
+		finishSweep()
+		return
+	}
+	tracker.state.Map = currentSettings().Map.String()
+	runSweep(tracker, reportFrequency, search, win)
+}
 
-		workDistributorChannel <- *n
-		workersDispatched++
-		nl.Add(&nl, oneBig)
+// calcStonesList runs a list of values in the order given
+func calcStonesList(values []*big.Int, reportFrequency int, search rangeSearch, win fyne.Window) {
+	if len(values) == 0 {
+		showInformation("List Error", "Load a list of values first", win)
+		finishSweep()
+		return
 	}
 
-	// Wait for the workers to finish
-	wg.Wait()
+	tracker := newListTracker(values)
+	tracker.state.Map = currentSettings().Map.String()
+	runSweep(tracker, reportFrequency, search, win)
+}
 
-	progress.Hide()
-	infProgress.Show()
-	// Stop the workers
-	for _, worker := range workersThreadSafeSlice.workers {
-		wg.Add(1)
-		worker.finishedChannel <- true
+// readValueList reads starting values one a line, each a value or an expression
+// in the base named by sb. Blank lines and lines starting with # are skipped and
+// a value listed again is only run once.
+func readValueList(r io.Reader, sb string) ([]*big.Int, error) {
+	var values []*big.Int
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+	for line := 1; scanner.Scan(); line++ {
+		text := removeSpaces(strings.TrimSpace(scanner.Text()))
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		v, err := evalExpression(text, parseEntryBase(sb))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if v.Sign() <= 0 {
+			return nil, fmt.Errorf("line %d: %s is not a positive value", line, text)
+		}
+		if !seen[v.String()] {
+			seen[v.String()] = true
+			values = append(values, v)
+		}
 	}
+	return values, scanner.Err()
+}
+func resumeStonesMulti(cp sweepCheckpoint, reportFrequency int, win fyne.Window) {
 
-	wg.Wait()
+	tracker, err := resumeSweepTracker(cp)
+	if err != nil {
+		showInformation("Resume Error", err.Error(), win)
+		finishSweep()
+		return
+	}
+	search, err := checkpointSearch(cp)
+	if err != nil {
+		showInformation("Resume Error", err.Error(), win)
+		finishSweep()
+		return
+	}
 
-	calcBtn.Enable()
-	stepBtn.Disable()
-	pauseBtn.Disable()
-	resumeBtn.Disable()
-	stopBtn.Disable()
+	runSweep(tracker, reportFrequency, search, win)
+}
+func runSweep(tracker *sweepTracker, reportFrequency int, search rangeSearch, win fyne.Window) {
+
+	// A resumed sweep carries on with the map it was started with
+	mapping, err := parseCollatzMap(tracker.state.Map)
+	if err != nil {
+		showInformation("Resume Error", fmt.Sprintf("Unable to resume with the checkpoint map: %v", err), win)
+		finishSweep()
+		return
+	}
+	session := newRunSession(tracker, mapping, results)
+	session.recordSteps = true
+	searchSession(session, search)
+
+	// A checkpoint that cannot be written is only reported once, as it is tried every few seconds
+	checkpointFailed := false
+	checkpoint := func(force bool) {
+		if err := session.Checkpoint(force); err != nil && !checkpointFailed {
+			checkpointFailed = true
+			showInformation("Checkpoint Error", fmt.Sprintf("Unable to write the checkpoint, so the run cannot be resumed: %v", err), win)
+		}
+	}
+	session.OnReport = func(sequenceReport sequenceProgress) {
+		snap := session.Snapshot()
+		if snap.Processed%int64(reportFrequency) == 0 {
+			showRangeProgress(snap)
+			if results != nil {
+				results.Flush()
+			}
+		}
+		checkpoint(false)
+		addHeatmapResult(sequenceReport)
+	}
+	setRangeRun(session)
+
+	// Start from the high water marks of the tracker, which are empty for a new sweep
+	showRangeProgress(session.Snapshot())
+	clearCharts()
+	startHeatmap(tracker)
+	refreshHeatmap()
+	showRunStatistics(nil, nil, nil, mapping)
+	showMatches(nil, search)
+	updateUI(func() {
+		progress.Show()
+	})
+
+	session.Run()
+
+	checkpoint(true)
+	if results != nil {
+		results.Flush()
+	}
+	snap := session.Snapshot()
+	if snap.StoreFailures > 0 {
+		showInformation("Database Error", fmt.Sprintf("Unable to store %d of the results: %v", snap.StoreFailures, snap.StoreError), win)
+	}
+	showRangeProgress(snap)
+
+	updateUI(func() {
+		progress.Hide()
+		infProgress.Show()
+	})
+
+	finishSweep()
 	//	return steps
 
-	refreshSequenceChart()
-	infProgress.Hide()
+	steps, numbers := session.Steps()
+	refreshSequenceChart(steps, numbers, mapping)
+	refreshHeatmap()
+	showRunStatistics(steps, session.StoppingTimes(), session.Magnitudes(), mapping)
+	showMatches(session.Matches(), search)
+	updateUI(func() {
+		infProgress.Hide()
+	})
+}
+func showRangeProgress(snap sessionSnapshot) {
+	percentFinished := 100.0
+	if snap.Total > 0 {
+		percentFinished = float64(snap.Processed) / float64(snap.Total) * 100
+	}
+
+	updateUI(func() {
+		highwaterStepsLabel.SetText(fmt.Sprintf("%d", snap.HighwaterSteps))
+		highwaterStepsNumberLabel.SetText(snap.HighwaterStepsNumber)
+		highwaterStoneLabel.SetText(snap.HighwaterStone)
+		highwaterStoneNumberLabel.SetText(snap.HighwaterStoneNumber)
+		progress.SetValue(percentFinished)
+	})
+}
+
+// calcStonesCoordinated runs the range as a distributed sweep. This machine
+// listens for remote workers and also works on the range itself.
+func calcStonesCoordinated(lower string, upper string, base string, listen string, block string, win fyne.Window) {
+
+	nl, ok := checkValidation(lower, base, win)
+	if !ok {
+		finishSweep()
+		return
+	}
+
+	nu, ok := checkValidation(upper, base, win)
+	if !ok {
+		finishSweep()
+		return
+	}
+
+	blockSize, ok := checkValidation(removeSpaces(block), "Base 10", win)
+	if !ok {
+		finishSweep()
+		return
+	}
+
+	// The upper limit is included, as it is in a local run
+	coord, err := newCoordinator(&nl, new(big.Int).Add(&nu, oneBig), currentSettings().Map, blockSize.Int64(), defaultLeaseTimeout)
+	if err != nil {
+		showInformation("Number Format Error", err.Error(), win)
+		finishSweep()
+		return
+	}
+	addr, err := coord.Listen(listen)
+	if err != nil {
+		showInformation("Coordinator Error", fmt.Sprintf("Unable to listen on %s: %v", listen, err), win)
+		finishSweep()
+		return
+	}
+
+	updateUI(func() {
+		highwaterStepsLabel.SetText("")
+		highwaterStepsNumberLabel.SetText("")
+		highwaterStoneLabel.SetText("")
+		highwaterStoneNumberLabel.SetText("")
+		progress.SetValue(0)
+	})
+	clearCharts()
+	updateUI(func() {
+		progress.Show()
+	})
+
+	setRangeRun(coord)
+
+	stopLocal := make(chan bool)
+	go runRemoteWorker(addr.String(), "local", stopLocal)
+
+	ticker := time.NewTicker(time.Second)
+	running := true
+	for running {
+		select {
+		case <-coord.Done():
+			running = false
+		case <-coord.Stopped():
+			running = false
+		case <-ticker.C:
+			showCoordinatorProgress(coord)
+		}
+	}
+	ticker.Stop()
+	close(stopLocal)
+	coord.Close()
+
+	showCoordinatorProgress(coord)
+	finishSweep()
+}
+func showCoordinatorProgress(coord *coordinator) {
+	fraction, summary, workers := coord.Progress()
+
+	coordinatorWorkers.Set(workers)
+	updateUI(func() {
+		highwaterStepsLabel.SetText(fmt.Sprintf("%d", summary.HighwaterSteps))
+		highwaterStepsNumberLabel.SetText(summary.HighwaterStepsNumber)
+		highwaterStoneLabel.SetText(summary.HighwaterStone)
+		highwaterStoneNumberLabel.SetText(summary.HighwaterStoneNumber)
+		progress.SetValue(fraction * 100)
+		workersTable.Refresh()
+	})
+}
+
+// startSweep sets the Range tab buttons for a run in progress
+func startSweep() {
+	updateUI(func() {
+		calcBtn.Disable()
+		resumeRunBtn.Disable()
+		pauseBtn.Enable()
+		stepBtn.Enable()
+		stopBtn.Enable()
+	})
+}
+
+// pausedButtons swaps the Pause and Resume buttons
+func pausedButtons(paused bool) {
+	updateUI(func() {
+		if paused {
+			pauseBtn.Disable()
+			resumeBtn.Enable()
+		} else {
+			pauseBtn.Enable()
+			resumeBtn.Disable()
+		}
+	})
+}
+
+func finishSweep() {
+	updateUI(func() {
+		calcBtn.Enable()
+		resumeRunBtn.Enable()
+		stepBtn.Disable()
+		pauseBtn.Disable()
+		resumeBtn.Disable()
+		stopBtn.Disable()
+		progress.Hide()
+	})
 }
 
 func clearCharts() {
-	stonesChart.RemoveAll()
-	stonesChart.Refresh()
+	stonesChart.Clear()
+	stonesLogChart.Clear()
+	sequenceLengthChart.Clear()
+}
 
-	stonesLogChart.RemoveAll()
-	stonesLogChart.Refresh()
+// showStoneDetails opens the Details tab at the stone of a step
+func showStoneDetails(step float64) {
+	updateUI(func() {
+		singleStatusTabs.Select(detailsTab)
+		detailStoneList.Select(widget.TableCellID{Row: int(step) + 1, Col: 1})
+	})
+}
 
-	sequenceLengthChart.RemoveAll()
-	sequenceLengthChart.Refresh()
+// The most stones plotted on a hailstone chart, as a trajectory may have millions
+const maxChartPoints = 2000
+
+// absoluteShift returns the power of two the absolute chart divides its values
+// by, which is 0 unless the largest stone is beyond the range of float64
+func absoluteShift(maxStone *big.Int) uint {
+	if maxStone.BitLen() <= 1000 {
+		return 0
+	}
+	return uint(maxStone.BitLen() - 53)
 }
-func refreshAbsoluteChart(xV *[]float64, sequenceReport *sequenceProgress) {
-	graphAbsolute := chart.Chart{
-		Series: []chart.Series{
-			chart.ContinuousSeries{
-				XValues: *xV,
-				YValues: sequenceReport.stonesRaw,
-			},
-		},
-		YAxis: chart.YAxis{
-			Style:     chart.Shown(),
-			NameStyle: chart.Shown(),
-			Range:     &chart.ContinuousRange{},
+
+// hailstoneSeries charts a trajectory, as its stones divided by 2^shift or as
+// log2 of its stones. Zooming in samples the stones of the steps shown.
+type hailstoneSeries struct {
+	trajectory *trajectory
+	shift      uint
+	log        bool
+}
+
+func (s *hailstoneSeries) Bounds() (float64, float64) {
+	return 0, float64(s.trajectory.Len() - 1)
+}
+
+func (s *hailstoneSeries) value(stone *big.Int) float64 {
+	if s.log {
+		return log2Big(stone)
+	}
+	scaled, _ := new(big.Float).SetMantExp(new(big.Float).SetInt(stone), -int(s.shift)).Float64()
+	return scaled
+}
+
+func (s *hailstoneSeries) Points(from float64, to float64) (xV []float64, yV []float64) {
+	s.trajectory.Sample(int(math.Ceil(from)), int(math.Floor(to))+1, maxChartPoints, func(i int, stone *big.Int) {
+		xV = append(xV, float64(i))
+		yV = append(yV, s.value(stone))
+	})
+	return xV, yV
+}
+
+func (s *hailstoneSeries) Nearest(x float64) (float64, float64, string) {
+	i := int(math.Round(x))
+	if i < 0 {
+		i = 0
+	}
+	if i >= s.trajectory.Len() {
+		i = s.trajectory.Len() - 1
+	}
+	stone := s.trajectory.Stones(i, 1)[0]
+	return float64(i), s.value(stone), fmt.Sprintf("Step %d: %s", i, abbreviate(formatValue(stone)))
+}
+
+func (s *hailstoneSeries) Chart(xV []float64, yV []float64) chart.Chart {
+	series := []chart.Series{
+		chart.ContinuousSeries{
+			Style:   seriesStyle(currentSettings().ChartStyle),
+			XValues: xV,
+			YValues: yV,
 		},
 	}
-	bufferAbs := bytes.NewBuffer([]byte{})
-	graphAbsolute.Render(chart.PNG, bufferAbs)
 
-	stonesChart.RemoveAll()
-	stonesChart.Refresh() // ensures UI reflects the change
+	// The log chart is labelled in powers of two, and shows the drift the heuristic expects
+	if s.log {
+		ticksV := yV
+		if currentSettings().Heuristics {
+			drift := driftLine(log2Big(s.trajectory.Stones(0, 1)[0]), xV[0], xV[len(xV)-1], s.trajectory.mapping)
+			series = append(series, drift)
+			ticksV = append(append([]float64{}, yV...), drift.YValues...)
+		}
+		return chart.Chart{
+			Series: series,
+			YAxis: chart.YAxis{
+				Style:     chart.Shown(),
+				NameStyle: chart.Shown(),
+				Range:     &chart.ContinuousRange{},
+				Ticks:     powerOfTwoTicks(ticksV),
+			},
+		}
+	}
+
+	yAxis := chart.YAxis{
+		Style:     chart.Shown(),
+		NameStyle: chart.Shown(),
+		Range:     &chart.ContinuousRange{},
+	}
+	if s.shift > 0 {
+		yAxis.Name = fmt.Sprintf("Stone ÷ 2^%d", s.shift)
+	}
+	return chart.Chart{
+		Series: series,
+		YAxis:  yAxis,
+	}
+}
+
+// powerOfTwoTicks returns about ten ticks labelled 2^k spanning the log2 values
+func powerOfTwoTicks(logV []float64) []chart.Tick {
+	if len(logV) == 0 {
+		return nil
+	}
+	lo, hi := logV[0], logV[0]
+	for _, v := range logV {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+
+	// Step by 1, 2 or 5 times a power of ten
+	step := 1.0
+	for scale := 1.0; (hi-lo)/step > 10; scale *= 10 {
+		for _, m := range []float64{1, 2, 5} {
+			if step = m * scale; (hi-lo)/step <= 10 {
+				break
+			}
+		}
+	}
 
-	absCanvas := canvas.NewImageFromReader(bufferAbs, "chart.png")
-	absCanvas.SetMinSize(fyne.NewSize(200, 200))
+	var ticks []chart.Tick
+	for k := math.Floor(lo/step) * step; k <= math.Ceil(hi/step)*step; k += step {
+		ticks = append(ticks, chart.Tick{Value: k, Label: fmt.Sprintf("2^%.0f", k)})
+	}
+	return ticks
+}
 
-	stonesChart.Add(absCanvas)
-	stonesChart.Refresh()
+// log2Big returns log2 of a positive n exactly to float64 precision, from its
+// bit length and its leading 53 bits, however large n is
+func log2Big(n *big.Int) float64 {
+	shift := n.BitLen() - 53
+	if shift <= 0 {
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return math.Log2(f)
+	}
+	mantissa, _ := new(big.Float).SetInt(new(big.Int).Rsh(n, uint(shift))).Float64()
+	return float64(shift) + math.Log2(mantissa)
 }
 
-func refreshLogChart(xV *[]float64, sequenceReport *sequenceProgress) {
-	graphLog := chart.Chart{
-		Series: []chart.Series{
-			chart.ContinuousSeries{
-				XValues: *xV,
-				YValues: sequenceReport.stonesRaw,
-			},
-		},
-		YAxis: chart.YAxis{
-			Style:     chart.Shown(),
-			NameStyle: chart.Shown(),
-			Range:     &chart.LogarithmicRange{},
-		},
+// The most values plotted on the sequence length chart, which is a scatter of dots
+const maxScatterPoints = 20000
+
+// sequenceSeries charts the steps taken by each value of a range
+type sequenceSeries struct {
+	numbers []float64
+	steps   []float64
+	mapping collatzMap
+}
+
+// newSequenceSeries sorts the results by value, as they arrive in any order
+func newSequenceSeries(steps []float64, numbers []float64, m collatzMap) *sequenceSeries {
+	order := make([]int, len(numbers))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return numbers[order[a]] < numbers[order[b]]
+	})
+
+	s := &sequenceSeries{numbers: make([]float64, len(order)), steps: make([]float64, len(order)), mapping: m}
+	for i, idx := range order {
+		s.numbers[i] = numbers[idx]
+		s.steps[i] = steps[idx]
 	}
-	bufferLog := bytes.NewBuffer([]byte{})
-	graphLog.Render(chart.PNG, bufferLog)
+	return s
+}
 
-	stonesLogChart.RemoveAll()
-	stonesLogChart.Refresh() // ensures UI reflects the change
+func (s *sequenceSeries) Bounds() (float64, float64) {
+	return s.numbers[0], s.numbers[len(s.numbers)-1]
+}
 
-	logCanvas := canvas.NewImageFromReader(bufferLog, "chart.png")
-	logCanvas.SetMinSize(fyne.NewSize(200, 200))
+func (s *sequenceSeries) Points(from float64, to float64) (xV []float64, yV []float64) {
+	lo := sort.SearchFloat64s(s.numbers, from)
+	hi := sort.Search(len(s.numbers), func(i int) bool {
+		return s.numbers[i] > to
+	})
+	stride := 1
+	if hi-lo > maxScatterPoints {
+		stride = (hi - lo + maxScatterPoints - 1) / maxScatterPoints
+	}
+	for i := lo; i < hi; i += stride {
+		xV = append(xV, s.numbers[i])
+		yV = append(yV, s.steps[i])
+	}
+	return xV, yV
+}
 
-	stonesLogChart.Add(logCanvas)
-	stonesLogChart.Refresh()
+func (s *sequenceSeries) Nearest(x float64) (float64, float64, string) {
+	i := sort.SearchFloat64s(s.numbers, x)
+	if i == len(s.numbers) || (i > 0 && x-s.numbers[i-1] < s.numbers[i]-x) {
+		i--
+	}
+	return s.numbers[i], s.steps[i], fmt.Sprintf("%.0f: %.0f steps", s.numbers[i], s.steps[i])
 }
 
-func refreshSequenceChart() {
+func (s *sequenceSeries) Chart(xV []float64, yV []float64) chart.Chart {
 	viridisByY := func(xr, yr chart.Range, index int, x, y float64) drawing.Color {
 		return chart.Viridis(y, yr.GetMin(), yr.GetMax())
 	}
 
-	graphSeq := chart.Chart{
+	graph := chart.Chart{
 		XAxis: chart.XAxis{
 			Name: "The XAxis",
 			Style: chart.Style{
@@ -701,22 +1665,23 @@ func refreshSequenceChart() {
 					DotWidth:         1,
 					DotColorProvider: viridisByY,
 				},
-				XValues: stepsNumberSlice,
-				YValues: stepsSlice,
+				XValues: xV,
+				YValues: yV,
 			},
 		},
 	}
-	bufferSeq := bytes.NewBuffer([]byte{})
-	graphSeq.Render(chart.PNG, bufferSeq)
-
-	sequenceLengthChart.RemoveAll()
-	sequenceLengthChart.Refresh()
-
-	seqCanvas := canvas.NewImageFromReader(bufferSeq, "chart.png")
-	seqCanvas.SetMinSize(fyne.NewSize(400, 400))
+	if currentSettings().Heuristics {
+		graph.Series = append(graph.Series, heuristicCurve(xV[0], xV[len(xV)-1], s.mapping))
+	}
+	return graph
+}
 
-	sequenceLengthChart.Add(seqCanvas)
-	sequenceLengthChart.Refresh()
+func refreshSequenceChart(stepsSlice []float64, stepsNumberSlice []float64, m collatzMap) {
+	if len(stepsNumberSlice) == 0 {
+		sequenceLengthChart.Clear()
+		return
+	}
+	sequenceLengthChart.SetSeries(newSequenceSeries(stepsSlice, stepsNumberSlice, m))
 }
 
 func bigIntToFloat64(x *big.Int) (float64, error) {
@@ -736,30 +1701,137 @@ func bigIntToFloat64(x *big.Int) (float64, error) {
 	return f64, nil
 }
 
+// rememberEntries keeps the values of a calculation, and the base they were
+// entered in, for the next session. The default entry base is only changed in
+// the Settings dialog.
+func rememberEntries(values map[string]string) {
+	prefs := fyne.CurrentApp().Preferences()
+	for key, value := range values {
+		prefs.SetString(key, value)
+	}
+}
+
+// lastEntryBase returns the base the last values of a tab were entered in, or
+// the default entry base if there are none
+func lastEntryBase(key string) string {
+	base := currentSettings().EntryBase
+	return oneOf(fyne.CurrentApp().Preferences().StringWithFallback(key, base), entryBases(true), base)
+}
+
 func removeSpaces(s string) string {
 	return strings.ReplaceAll(s, " ", "")
 }
 
-func checkValidation(s string, sb string, win fyne.Window) (n big.Int, ok bool) {
-	if s == "" {
-		return *zeroBig, false
+// entryBases returns the names of the bases offered by the base selects, with Auto first if auto is set
+func entryBases(auto bool) []string {
+	var bases []string
+	if auto {
+		bases = append(bases, "Auto")
+	}
+	for base := 2; base <= 62; base++ {
+		bases = append(bases, fmt.Sprintf("Base %d", base))
+	}
+	return bases
+}
+
+// parseEntryBase returns the base named by a base select, or 0 for Auto
+func parseEntryBase(sb string) int {
+	base, err := strconv.Atoi(strings.TrimPrefix(sb, "Base "))
+	if err != nil || base < 2 || base > 62 {
+		return 0
+	}
+	return base
+}
+
+// parsePrefixed reads a value in the base given by a 0x, 0b or 0o prefix, or in decimal without one
+func parsePrefixed(s string) (*big.Int, bool) {
+	sign := ""
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		sign, s = s[:1], s[1:]
+	}
+
+	base := 10
+	if len(s) > 2 && s[0] == '0' {
+		switch s[1] {
+		case 'x', 'X':
+			base = 16
+		case 'b', 'B':
+			base = 2
+		case 'o', 'O':
+			base = 8
+		}
 	}
-	number := new(big.Int)
+	if base != 10 {
+		s = s[2:]
+	}
+	if strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-") {
+		return nil, false
+	}
+	return new(big.Int).SetString(sign+s, base)
+}
 
-	var base int
-	switch sb {
-	case "Base 2":
-		base = 2
-	case "Base 10":
+// formatValue writes n in the display base
+func formatValue(n *big.Int) string {
+	base := int(displayBase.Load())
+	if base == 0 {
 		base = 10
-	case "Base 16":
-		base = 16
-	case "Base 36":
-		base = 36
 	}
-	_, ok = number.SetString(s, base)
+	return n.Text(base)
+}
+
+// The most digits shown for a value before the middle is left out
+const maxShownDigits = 60
+
+// abbreviate shortens a value with thousands of digits to its ends and its length
+func abbreviate(s string) string {
+	if len(s) <= maxShownDigits {
+		return s
+	}
+	return fmt.Sprintf("%s…%s (%d digits)", s[:maxShownDigits/2], s[len(s)-maxShownDigits/2:], len(s))
+}
+
+// formatDecimal writes a decimal value in the display base
+func formatDecimal(s string) string {
+	n, ok := new(big.Int).SetString(s, 10)
 	if !ok {
-		dialog.ShowInformation("Number Format Error", fmt.Sprintf("The entry %s is not a valid input for Base %d", s, base), win)
+		return s
+	}
+	return formatValue(n)
+}
+
+// expressionValidator checks an entry holds an expression of at least 1 in the base chosen by baseSelect.
+// The error is shown under the entry while typing, so no dialog is needed until Calculate.
+func expressionValidator(baseSelect *widget.Select) fyne.StringValidator {
+	return func(s string) error {
+		if s == "" {
+			return nil
+		}
+		_, err := evalPositive(s, parseEntryBase(baseSelect.Selected))
+		return err
+	}
+}
+
+// residueValidator is expressionValidator for a residue, which may be 0
+func residueValidator(baseSelect *widget.Select) fyne.StringValidator {
+	return func(s string) error {
+		if s == "" {
+			return nil
+		}
+		_, err := evalNonNegative(s, parseEntryBase(baseSelect.Selected))
+		return err
+	}
+}
+
+// checkValidation evaluates an entry, which may be an expression such as 2^100+1, in the base named by sb.
+// The value must be at least 1, as every value, limit and count entered must be.
+func checkValidation(s string, sb string, win fyne.Window) (n big.Int, ok bool) {
+	if s == "" {
+		return *zeroBig, false
+	}
+
+	number, err := evalPositive(s, parseEntryBase(sb))
+	if err != nil {
+		showInformation("Number Format Error", fmt.Sprintf("The entry %s is not a valid input for %s: %v", s, sb, err), win)
 		return *zeroBig, false
 	}
 	return *number, true
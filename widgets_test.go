@@ -0,0 +1,783 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+var testPoolOnce sync.Once
+
+// startTestPool starts the worker pool the tests share
+func startTestPool() {
+	testPoolOnce.Do(func() {
+		startWorkerPool(8)
+		go handleSingleModeStatusReport()
+	})
+}
+
+// newTestUI builds the visualiser on the Fyne test driver and returns its tabs
+func newTestUI(t *testing.T) (fyne.Window, *container.AppTabs) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	a := test.NewApp()
+	t.Cleanup(a.Quit)
+	startTestPool()
+
+	w := a.NewWindow("Collatz Visualisation")
+	w.SetContent(makeEntryTab(w))
+	w.Resize(fyne.NewSize(900, 800))
+
+	for _, o := range test.LaidOutObjects(w.Content()) {
+		if tabs, ok := o.(*container.AppTabs); ok {
+			return w, tabs
+		}
+	}
+	t.Fatal("no tabs found")
+	return nil, nil
+}
+
+// entriesOf returns the entries of a tab in the order they are laid out
+func entriesOf(o fyne.CanvasObject) []*widget.Entry {
+	var entries []*widget.Entry
+	for _, child := range test.LaidOutObjects(o) {
+		if entry, ok := child.(*widget.Entry); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// answerDialog taps the button of the dialog shown over the window
+func answerDialog(t *testing.T, w fyne.Window, answer string) {
+	t.Helper()
+	var button *widget.Button
+	waitFor(t, "the dialog", func() bool {
+		top := w.Canvas().Overlays().Top()
+		if top == nil {
+			return false
+		}
+		for _, o := range test.LaidOutObjects(top) {
+			if b, ok := o.(*widget.Button); ok && b.Text == answer {
+				button = b
+				return true
+			}
+		}
+		return false
+	})
+	test.Tap(button)
+}
+
+// onUI runs f on the UI update goroutine and waits for it
+func onUI(f func()) {
+	done := make(chan bool)
+	updateUI(func() {
+		f()
+		close(done)
+	})
+	<-done
+}
+
+// waitFor polls the widgets on the UI goroutine until check passes
+func waitFor(t *testing.T, what string, check func() bool) {
+	t.Helper()
+	waitUntil(t, what, func() bool {
+		var ok bool
+		onUI(func() {
+			ok = check()
+		})
+		return ok
+	})
+}
+
+// waitUntil polls check until it passes
+func waitUntil(t *testing.T, what string, check func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		if check() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// chartImage waits for a chart to be drawn and decodes it
+func chartImage(t *testing.T, c *chartView) image.Image {
+	t.Helper()
+
+	var res fyne.Resource
+	waitFor(t, "the chart", func() bool {
+		if c.image.Visible() {
+			res = c.image.Resource
+		}
+		return res != nil
+	})
+	img, err := png.Decode(bytes.NewReader(res.Content()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestSingleValueSummary(t *testing.T) {
+	_, tabs := newTestUI(t)
+
+	entries := entriesOf(tabs.Items[0].Content)
+	if len(entries) != 1 {
+		t.Fatalf("expected one entry on the Single Value tab, found %d", len(entries))
+	}
+	test.Type(entries[0], "27")
+	test.Tap(calcSingleBtn)
+
+	waitFor(t, "the summary", func() bool {
+		return seqLen.Text == "111" && number.Text == "27"
+	})
+	onUI(func() {
+		if maxStone.Text != "9232" {
+			t.Errorf("max stone is %q, expected 9232", maxStone.Text)
+		}
+		if numUp.Text != "41" || numDown.Text != "70" {
+			t.Errorf("up/down is %s/%s, expected 41/70", numUp.Text, numDown.Text)
+		}
+		if expectedSeqLen.Text != "34.4, +76.6 observed" || oddDeviation.Text != "+1.43σ" {
+			t.Errorf("the deviation is %q and %q, expected 34.4, +76.6 observed and +1.43σ", expectedSeqLen.Text, oddDeviation.Text)
+		}
+	})
+
+	if stoneRows.Len() != 112 {
+		t.Errorf("the details table has %d stones, expected 112", stoneRows.Len())
+	}
+	if row, _ := stoneRows.Row(77); row.stone == nil || row.stone.String() != "9232" {
+		t.Errorf("stone 77 is %v, expected 9232", row.stone)
+	}
+
+	test.AssertImageMatches(t, "charts/absolute_27.png", chartImage(t, stonesChart))
+	test.AssertImageMatches(t, "charts/log_27.png", chartImage(t, stonesLogChart))
+}
+
+func TestSingleValueBeyondFloat64(t *testing.T) {
+	_, tabs := newTestUI(t)
+
+	entries := entriesOf(tabs.Items[0].Content)
+	test.Type(entries[0], "mersenne(2000)")
+	test.Tap(calcSingleBtn)
+
+	test.AssertImageMatches(t, "charts/absolute_mersenne_2000.png", chartImage(t, stonesChart))
+	test.AssertImageMatches(t, "charts/log_mersenne_2000.png", chartImage(t, stonesLogChart))
+}
+
+func TestChartInteraction(t *testing.T) {
+	_, tabs := newTestUI(t)
+
+	entries := entriesOf(tabs.Items[0].Content)
+	test.Type(entries[0], "27")
+	test.Tap(calcSingleBtn)
+
+	c := stonesLogChart
+	chartImage(t, c)
+	onUI(func() {
+		c.Resize(fyne.NewSize(1024, 400))
+	})
+	pointAt := func(x float64, y float64) fyne.Position {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		return c.positionOf(x, y)
+	}
+
+	// Hovering shows the step and stone under the mouse
+	peak := pointAt(77, math.Log2(9232))
+	c.MouseMoved(&desktop.MouseEvent{PointEvent: fyne.PointEvent{Position: peak.Add(fyne.NewPos(2, 30))}})
+	waitFor(t, "the tooltip", func() bool {
+		return c.tipText.Visible() && c.tipText.Text == "Step 77: 9232"
+	})
+	onUI(func() {
+		if c.crossX.Position1.X != peak.X || c.crossY.Position1.Y != peak.Y {
+			t.Errorf("the crosshair is at %v, %v, expected %v", c.crossX.Position1.X, c.crossY.Position1.Y, peak)
+		}
+	})
+
+	// Clicking opens the stone in the Details table
+	var selected widget.TableCellID
+	onUI(func() {
+		detailStoneList.OnSelected = func(id widget.TableCellID) {
+			selected = id
+		}
+	})
+	c.Tapped(&fyne.PointEvent{Position: peak})
+	waitFor(t, "the details row", func() bool {
+		return selected.Row == 78 && singleStatusTabs.Selected() == detailsTab
+	})
+
+	// Dragging zooms, scrolling pans and a double tap shows it all again
+	waitForRange := func(what string, from float64, to float64) {
+		t.Helper()
+		waitFor(t, what, func() bool {
+			shownFrom, shownTo := c.shownRange()
+			return math.Abs(shownFrom-from) < 0.5 && math.Abs(shownTo-to) < 0.5
+		})
+	}
+	start, end := pointAt(20, 0), pointAt(40, 0)
+	c.Dragged(&fyne.DragEvent{PointEvent: fyne.PointEvent{Position: end}, Dragged: fyne.NewDelta(end.X-start.X, 0)})
+	c.DragEnd()
+	waitForRange("the zoom", 20, 40)
+
+	c.lock.Lock()
+	topLeft, bottomRight := c.plotCorners()
+	c.lock.Unlock()
+	c.Scrolled(&fyne.ScrollEvent{Scrolled: fyne.NewDelta(0, -(bottomRight.X-topLeft.X)/2)})
+	waitForRange("the pan", 30, 50)
+	c.Scrolled(&fyne.ScrollEvent{Scrolled: fyne.NewDelta(0, -10000)})
+	waitForRange("the pan to the end", 91, 111)
+
+	c.DoubleTapped(&fyne.PointEvent{})
+	waitForRange("the reset", 0, 111)
+}
+
+func TestLog2Big(t *testing.T) {
+	for _, c := range []struct {
+		n    *big.Int
+		want float64
+	}{
+		{big.NewInt(1), 0},
+		{big.NewInt(8), 3},
+		{big.NewInt(9232), math.Log2(9232)},
+		{new(big.Int).Lsh(oneBig, 5000), 5000},
+		{new(big.Int).Lsh(big.NewInt(3), 100000), 100000 + math.Log2(3)},
+	} {
+		if got := log2Big(c.n); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("log2Big of a %d bit value is %v, expected %v", c.n.BitLen(), got, c.want)
+		}
+	}
+}
+
+func TestHistoryPanel(t *testing.T) {
+	_, tabs := newTestUI(t)
+
+	entries := entriesOf(tabs.Items[0].Content)
+	test.Type(entries[0], "27")
+	test.Tap(calcSingleBtn)
+	waitFor(t, "the first value", func() bool {
+		return seqLen.Text == "111" && historyRows.Len() == 1
+	})
+
+	onUI(func() {
+		entries[0].SetText("")
+	})
+	test.Type(entries[0], "2^10-1")
+	test.Tap(calcSingleBtn)
+	waitFor(t, "the second value", func() bool {
+		return number.Text == "1023" && historyRows.Len() == 2
+	})
+	if row, _ := historyRows.Row(0); row.Input != "2^10-1" || historyText(row) != "2^10-1\n62 steps, max stone 118096" {
+		t.Errorf("the top of the history is %q", historyText(row))
+	}
+
+	// Selecting the older value puts it back and calculates it again
+	historyList.Select(1)
+	waitFor(t, "the value from the history", func() bool {
+		return number.Text == "27" && seqLen.Text == "111" && entries[0].Text == "27"
+	})
+	if row, _ := historyRows.Row(0); row.Number != "27" {
+		t.Errorf("the top of the history is %s, expected 27", row.Number)
+	}
+}
+
+func TestSingleValueInvalidEntry(t *testing.T) {
+	_, tabs := newTestUI(t)
+
+	entries := entriesOf(tabs.Items[0].Content)
+	test.Type(entries[0], "12x")
+	test.Tap(calcSingleBtn)
+
+	// Nothing is calculated, so the summary stays empty
+	time.Sleep(100 * time.Millisecond)
+	onUI(func() {
+		if seqLen.Text != "" {
+			t.Errorf("sequence length is %q for an invalid entry", seqLen.Text)
+		}
+	})
+}
+
+func TestDatabaseInvalidFilter(t *testing.T) {
+	store := openTestStore(t, filepath.Join(t.TempDir(), storeFile))
+	defer store.Close()
+	storeValues(t, store, 1, 1000)
+	saved := results
+	t.Cleanup(func() {
+		results = saved
+	})
+	results = store
+	w, tabs := newTestUI(t)
+
+	var search *widget.Button
+	for _, o := range test.LaidOutObjects(tabs.Items[2].Content) {
+		if b, ok := o.(*widget.Button); ok && b.Text == "Search" {
+			search = b
+		}
+	}
+	// found returns the count shown once a search has run
+	found := func() string {
+		for _, o := range test.LaidOutObjects(tabs.Items[2].Content) {
+			if l, ok := o.(*widget.Label); ok && strings.HasSuffix(l.Text, "stored values") {
+				return l.Text
+			}
+		}
+		return ""
+	}
+
+	entries := entriesOf(tabs.Items[2].Content)
+	test.Type(entries[1], "170")
+	test.Type(entries[2], "17x")
+	test.Tap(search)
+
+	// The query is not run without the filter that is not valid
+	answerDialog(t, w, "OK")
+	onUI(func() {
+		if count := found(); count != "" {
+			t.Errorf("the search found %q with an invalid entry", count)
+		}
+	})
+
+	entries[2].SetText("175")
+	test.Tap(search)
+	waitFor(t, "the search", func() bool {
+		return found() == "2 of 1000 stored values"
+	})
+}
+
+func TestSequenceLengthChart(t *testing.T) {
+	newTestUI(t)
+
+	var steps, numbers []float64
+	for n := int64(1); n <= 1000; n++ {
+		report := CollatzPerf(*big.NewInt(n), standardMap)
+		steps = append(steps, float64(report.steps))
+		numbers = append(numbers, float64(n))
+	}
+	refreshSequenceChart(steps, numbers, standardMap)
+	waitForUI()
+
+	test.AssertImageMatches(t, "charts/sequence_length_1000.png", chartImage(t, sequenceLengthChart))
+}
+
+func TestRangeHighwaterMarks(t *testing.T) {
+	_, tabs := newTestUI(t)
+	tabs.SelectIndex(1)
+
+	entries := entriesOf(tabs.Items[1].Content)
+	if len(entries) < 3 {
+		t.Fatalf("expected the range entries, found %d", len(entries))
+	}
+	test.Type(entries[0], "1")
+	test.Type(entries[1], "20000")
+	test.Tap(calcBtn)
+
+	waitFor(t, "the run to finish", func() bool {
+		return !calcBtn.Disabled() && highwaterStepsLabel.Text != ""
+	})
+	onUI(func() {
+		if highwaterStepsLabel.Text != "278" || highwaterStepsNumberLabel.Text != "17647" {
+			t.Errorf("longest sequence is %s at %s, expected 278 at 17647", highwaterStepsLabel.Text, highwaterStepsNumberLabel.Text)
+		}
+		// 9663 and 17647 both reach the largest stone, whichever comes back first is shown
+		if highwaterStoneLabel.Text != "27114424" {
+			t.Errorf("largest stone is %s, expected 27114424", highwaterStoneLabel.Text)
+		}
+		if !pauseBtn.Disabled() || !stopBtn.Disabled() {
+			t.Error("the run controls are still enabled after the run")
+		}
+	})
+
+	waitFor(t, "the heatmap", func() bool {
+		return heatmapCanvas.Visible() && heatmapCaption.Text != ""
+	})
+	onUI(func() {
+		if heatmapCaption.Text != "20000 values on a grid of 142 × 141" {
+			t.Errorf("the heatmap caption is %q", heatmapCaption.Text)
+		}
+	})
+	test.AssertImageMatches(t, "charts/heatmap_20000.png", heatmapCanvas.Image)
+}
+
+func TestRangeStride(t *testing.T) {
+	_, tabs := newTestUI(t)
+	tabs.SelectIndex(1)
+
+	// n ≡ 27 (mod 64) up to and including 283
+	entries := entriesOf(tabs.Items[1].Content)
+	for i, text := range []string{"1", "283", "64", "27"} {
+		test.Type(entries[i], text)
+	}
+	previous := currentRangeSession()
+	test.Tap(calcBtn)
+
+	waitFor(t, "the run to finish", func() bool {
+		return currentRangeSession() != previous && !calcBtn.Disabled() && highwaterStepsLabel.Text != ""
+	})
+	longest, longestNumber := 0, int64(0)
+	for n := int64(27); n <= 283; n += 64 {
+		if steps := CollatzPerf(*big.NewInt(n), standardMap).steps; steps > longest {
+			longest, longestNumber = steps, n
+		}
+	}
+	if snap := currentRangeSession().Snapshot(); snap.Total != 5 || snap.Processed != 5 {
+		t.Errorf("%d of %d values were run, expected 5", snap.Processed, snap.Total)
+	}
+	onUI(func() {
+		if highwaterStepsLabel.Text != strconv.Itoa(longest) || highwaterStepsNumberLabel.Text != strconv.FormatInt(longestNumber, 10) {
+			t.Errorf("longest sequence is %s at %s, expected %d at %d", highwaterStepsLabel.Text, highwaterStepsNumberLabel.Text, longest, longestNumber)
+		}
+	})
+
+	// The multiples of 64 have the residue 0
+	entries[3].SetText("")
+	test.Type(entries[3], "0")
+	previous = currentRangeSession()
+	test.Tap(calcBtn)
+	waitFor(t, "the run to finish", func() bool {
+		return currentRangeSession() != previous && !calcBtn.Disabled()
+	})
+	if snap, state := currentRangeSession().Snapshot(), currentRangeSession().State(); snap.Processed != 4 || state.Lower != "64" {
+		t.Errorf("%d values were run from %s, expected 4 from 64", snap.Processed, state.Lower)
+	}
+}
+
+func TestRangeSample(t *testing.T) {
+	_, tabs := newTestUI(t)
+	tabs.SelectIndex(1)
+
+	for _, o := range test.LaidOutObjects(tabs.Items[1].Content) {
+		if radio, ok := o.(*widget.RadioGroup); ok {
+			radio.SetSelected("Sample")
+		}
+	}
+	entries := entriesOf(tabs.Items[1].Content)
+	test.Type(entries[0], "2^200")
+	test.Type(entries[1], "2^201")
+	entries[4].SetText("")
+	test.Type(entries[4], "200")
+	test.Type(entries[5], "7")
+	previous := currentRangeSession()
+	test.Tap(calcBtn)
+
+	waitFor(t, "the run to finish", func() bool {
+		return currentRangeSession() != previous && !calcBtn.Disabled() && statisticsText.String() != ""
+	})
+	session := currentRangeSession()
+	if snap := session.Snapshot(); snap.Total != 200 || snap.Processed != 200 {
+		t.Errorf("%d of %d values were run, expected 200", snap.Processed, snap.Total)
+	}
+
+	// The values are around 2^200 and take close to the steps the heuristic expects
+	steps, _ := session.Steps()
+	stats := newRunStatistics(steps, session.StoppingTimes(), session.Magnitudes(), standardMap)
+	if stats.meanLog2 < 200 || stats.meanLog2 > 201 || math.Abs(stats.stepsPerBit-stats.expected) > 0.5 {
+		t.Errorf("a mean log2 of %.2f takes %.3f steps a bit, expected about %.3f", stats.meanLog2, stats.stepsPerBit, stats.expected)
+	}
+	onUI(func() {
+		if !strings.Contains(statisticsText.String(), "Values: 200") {
+			t.Errorf("the statistics are %q", statisticsText.String())
+		}
+	})
+	chartImage(t, magnitudeChart)
+}
+
+func TestRangeSearch(t *testing.T) {
+	w, tabs := newTestUI(t)
+	tabs.SelectIndex(1)
+
+	// replace answers the question asked before an unfinished run is replaced
+	run := func(upper string, search string, stopAfter string, replace bool) *RunSession {
+		t.Helper()
+		entries := entriesOf(tabs.Items[1].Content)
+		for i, text := range map[int]string{0: "1", 1: upper, 6: search, 7: stopAfter} {
+			entries[i].SetText("")
+			test.Type(entries[i], text)
+		}
+		previous := currentRangeSession()
+		test.Tap(calcBtn)
+		if replace {
+			answerDialog(t, w, "Yes")
+		}
+		// The last progress bar is hidden once the matches are shown
+		waitFor(t, "the search to finish", func() bool {
+			return currentRangeSession() != previous && !calcBtn.Disabled() && !infProgress.Visible()
+		})
+		return currentRangeSession()
+	}
+	brute := func(upper int64, holds func(n int64, r sequenceProgress) bool) (matches []int64) {
+		for n := int64(1); n <= upper; n++ {
+			if r := CollatzPerf(*big.NewInt(n), standardMap); holds(n, r) {
+				matches = append(matches, n)
+			}
+		}
+		return matches
+	}
+	shown := func() (numbers []int64) {
+		for i := 0; i < searchMatches.Len(); i++ {
+			m, _ := searchMatches.Row(i)
+			numbers = append(numbers, m.number.Int64())
+		}
+		return numbers
+	}
+
+	// The smallest values with exactly 100 steps, whatever order the workers finish in
+	session := run("20000", "steps == 100", "3", false)
+	want := brute(20000, func(n int64, r sequenceProgress) bool { return r.steps == 100 })[:3]
+	if got := shown(); !reflect.DeepEqual(got, want) {
+		t.Errorf("the matches are %v, expected %v", got, want)
+	}
+	if snap := session.Snapshot(); snap.Processed >= snap.Total {
+		t.Errorf("all %d values were run, expected the search to stop", snap.Total)
+	}
+	onUI(func() {
+		if matchesLabel.Text != "The first 3 matches of steps == 100" {
+			t.Errorf("the matches are headed %q", matchesLabel.Text)
+		}
+	})
+
+	// The checkpoint keeps the search, which carries on from its matches when resumed
+	cp, err := loadCheckpoint()
+	if err != nil || cp.Search != "steps == 100" || cp.SearchLimit != 3 || len(cp.Matches) < 3 {
+		t.Fatalf("the checkpoint searched %q for %d with %v (%v), expected steps == 100 for 3", cp.Search, cp.SearchLimit, cp.Matches, err)
+	}
+	cp.SearchLimit = 20
+	if err := saveCheckpoint(cp); err != nil {
+		t.Fatal(err)
+	}
+	previous := currentRangeSession()
+	test.Tap(resumeRunBtn)
+	waitFor(t, "the resumed search to finish", func() bool {
+		return currentRangeSession() != previous && !calcBtn.Disabled() && !infProgress.Visible()
+	})
+	want = brute(20000, func(n int64, r sequenceProgress) bool { return r.steps == 100 })[:20]
+	if got := shown(); !reflect.DeepEqual(got, want) {
+		t.Errorf("the resumed matches are %v, expected %v", got, want)
+	}
+
+	// A new run only replaces the unfinished search when told to
+	resumed := currentRangeSession()
+	test.Tap(calcBtn)
+	answerDialog(t, w, "No")
+	waitFor(t, "the run to be given up", func() bool {
+		return !calcBtn.Disabled()
+	})
+	if cp, err := loadCheckpoint(); err != nil || cp.Search != "steps == 100" || currentRangeSession() != resumed {
+		t.Errorf("the checkpoint searched %q (%v), expected the search kept", cp.Search, err)
+	}
+
+	// Every value whose peak is above its square
+	run("2000", "peak > n^2", "", true)
+	want = brute(2000, func(n int64, r sequenceProgress) bool { return r.maxStoneInt.Int64() > n*n })
+	if got := shown(); !reflect.DeepEqual(got, want) {
+		t.Errorf("the matches are %v, expected %v", got, want)
+	}
+	onUI(func() {
+		if matchesLabel.Text != fmt.Sprintf("%d matches of peak > n^2", len(want)) {
+			t.Errorf("the matches are headed %q", matchesLabel.Text)
+		}
+	})
+}
+
+func TestReadValueList(t *testing.T) {
+	values, err := readValueList(strings.NewReader("# starting values\n27\n\n0x61\n 2^10 + 1 \n27\n"), "Auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range values {
+		got = append(got, v.String())
+	}
+	if strings.Join(got, ",") != "27,97,1025" {
+		t.Errorf("the list is %v, expected 27, 97 and 1025", got)
+	}
+
+	if _, err := readValueList(strings.NewReader("ff\n27\n"), "Base 10"); err == nil || !strings.HasPrefix(err.Error(), "line 1:") {
+		t.Errorf("ff in base 10 gives %v, expected an error on line 1", err)
+	}
+	if _, err := readValueList(strings.NewReader("5\n0\n"), "Base 10"); err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("0 gives %v, expected an error on line 2", err)
+	}
+}
+
+func TestRangePauseResumeStop(t *testing.T) {
+	_, tabs := newTestUI(t)
+	tabs.SelectIndex(1)
+
+	entries := entriesOf(tabs.Items[1].Content)
+	test.Type(entries[0], "1")
+	test.Type(entries[1], "100000000")
+	previous := currentRangeSession()
+	test.Tap(calcBtn)
+
+	waitFor(t, "the run to start", func() bool {
+		return currentRangeSession() != previous && !pauseBtn.Disabled()
+	})
+	session := currentRangeSession()
+
+	test.Tap(pauseBtn)
+	waitFor(t, "the pause", func() bool {
+		return pauseBtn.Disabled() && !resumeBtn.Disabled()
+	})
+
+	// Once paused nothing more is handed to the workers
+	time.Sleep(100 * time.Millisecond)
+	before := session.Snapshot().Dispatched
+	time.Sleep(200 * time.Millisecond)
+	if after := session.Snapshot().Dispatched; after != before {
+		t.Errorf("%d values were dispatched while paused", after-before)
+	}
+
+	test.Tap(stepBtn)
+	waitFor(t, "the step", func() bool {
+		return session.Snapshot().Dispatched == before+1
+	})
+
+	test.Tap(resumeBtn)
+	waitFor(t, "the resume", func() bool {
+		return session.Snapshot().Dispatched > before+1 && !pauseBtn.Disabled()
+	})
+
+	test.Tap(stopBtn)
+	select {
+	case <-session.Done():
+	case <-time.After(30 * time.Second):
+		t.Fatal("the run did not stop")
+	}
+	waitFor(t, "the buttons to reset", func() bool {
+		return !calcBtn.Disabled() && !resumeRunBtn.Disabled() && stopBtn.Disabled()
+	})
+
+	snap := session.Snapshot()
+	if snap.Status != sessionCancelled {
+		t.Errorf("the run is %s, expected %s", snap.Status, sessionCancelled)
+	}
+	if snap.Processed >= snap.Total {
+		t.Error("the stopped run processed the whole range")
+	}
+}
+
+func FuzzCheckValidation(f *testing.F) {
+	a := test.NewApp()
+	defer a.Quit()
+	w := a.NewWindow("Collatz Visualisation")
+
+	bases := entryBases(false)
+
+	for _, seed := range []string{"", "0", "1", "27", "101", "ff", "FF", "zz", "-5", "+7", "1 0", "12x", "0x1f", "989345275647", "2^100+1", "(1", "3!!", "mersenne(7)"} {
+		for _, idx := range []uint8{0, 8, 14, 34, 60} {
+			f.Add(seed, idx)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, s string, which uint8) {
+		idx := int(which) % len(bases)
+		radix := idx + 2
+		n, ok := checkValidation(s, bases[idx], w)
+
+		// Every plain number of at least 1 is also a valid expression, and nothing less is
+		want, wantOK := new(big.Int).SetString(s, radix)
+		if s != "" && wantOK && want.Sign() > 0 && (!ok || n.Cmp(want) != 0) {
+			t.Fatalf("checkValidation(%q, %s) = %s, %v, expected %s", s, bases[idx], n.String(), ok, want)
+		}
+		if ok && n.Sign() < 1 {
+			t.Fatalf("checkValidation(%q, %s) accepted %s", s, bases[idx], n.String())
+		}
+		if !ok {
+			if n.Sign() != 0 {
+				t.Fatalf("checkValidation(%q, %s) returned %s with an error", s, bases[idx], n.String())
+			}
+			return
+		}
+
+		// The value written back out in the base parses to the same value
+		again, ok := checkValidation(n.Text(radix), bases[idx], w)
+		if !ok || again.Cmp(&n) != 0 {
+			t.Fatalf("%s in %s does not round trip", n.String(), bases[idx])
+		}
+	})
+}
+
+func TestCheckValidationPrefixes(t *testing.T) {
+	a := test.NewApp()
+	defer a.Quit()
+	w := a.NewWindow("Collatz Visualisation")
+
+	for _, c := range []struct {
+		entry string
+		want  string
+		ok    bool
+	}{
+		{"27", "27", true},
+		{"0x1b", "27", true},
+		{"0X1B", "27", true},
+		{"0b11011", "27", true},
+		{"0o33", "27", true},
+		{"033", "33", true},
+		{"-0x1b", "", false},
+		{"0x", "", false},
+		{"0x-1b", "", false},
+		{"0b102", "", false},
+		{"1_000", "", false},
+	} {
+		n, ok := checkValidation(c.entry, "Auto", w)
+		if ok != c.ok || (ok && n.String() != c.want) {
+			t.Errorf("checkValidation(%q, Auto) = %s, %v, expected %s, %v", c.entry, n.String(), ok, c.want, c.ok)
+		}
+	}
+
+	if n, ok := checkValidation("Zz", "Base 62", w); !ok || n.Int64() != 61*62+35 {
+		t.Errorf("Zz in base 62 is %s, %v", n.String(), ok)
+	}
+}
+
+func TestDisplayBase(t *testing.T) {
+	_, tabs := newTestUI(t)
+	t.Cleanup(func() {
+		displayBase.Store(10)
+	})
+
+	var displaySelect *widget.Select
+	for _, o := range test.LaidOutObjects(tabs.Items[0].Content) {
+		if s, ok := o.(*widget.Select); ok && s.Selected == "Base 10" && len(s.Options) == 61 {
+			displaySelect = s
+		}
+	}
+	if displaySelect == nil {
+		t.Fatal("no display base select found")
+	}
+
+	entries := entriesOf(tabs.Items[0].Content)
+	test.Type(entries[0], "27")
+	test.Tap(calcSingleBtn)
+	waitFor(t, "the summary", func() bool {
+		return seqLen.Text == "111"
+	})
+
+	displaySelect.SetSelected("Base 2")
+	waitFor(t, "the binary summary", func() bool {
+		return number.Text == "11011" && maxStone.Text == "10010000010000"
+	})
+	if row, _ := stoneRows.Row(1); formatValue(row.stone) != "1010010" {
+		t.Errorf("the second stone is %s in binary, expected 1010010", formatValue(row.stone))
+	}
+}